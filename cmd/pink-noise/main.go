@@ -12,8 +12,10 @@ import (
 
 	"github.com/agusx1211/pink-noise/internal/audio"
 	"github.com/agusx1211/pink-noise/internal/config"
+	"github.com/agusx1211/pink-noise/internal/filter"
 	"github.com/agusx1211/pink-noise/internal/mixer"
 	"github.com/agusx1211/pink-noise/internal/mqtt"
+	"github.com/agusx1211/pink-noise/internal/noise"
 )
 
 type PersistedState struct {
@@ -23,12 +25,97 @@ type PersistedState struct {
 	Treble       float64 `json:"treble"`
 	Preset       string  `json:"preset"`
 	Power        bool    `json:"power"`
+	StereoWidth  float64 `json:"stereo_width"`
+
+	BassFrequency   float64 `json:"bass_frequency,omitempty"`
+	TrebleFrequency float64 `json:"treble_frequency,omitempty"`
+	ShelfSlope      float64 `json:"shelf_slope,omitempty"`
+
+	Tilt       float64 `json:"tilt"`
+	TiltCenter float64 `json:"tilt_center,omitempty"`
+
+	TimerDeadline int64    `json:"timer_deadline,omitempty"` // unix seconds
+	FadeIn        *float64 `json:"fade_in,omitempty"`
+	FadeOut       *float64 `json:"fade_out,omitempty"`
+
+	HeartbeatBPM   float64 `json:"heartbeat_bpm,omitempty"`
+	HeartbeatLevel float64 `json:"heartbeat_level"`
+
+	ToneMode    string  `json:"tone_mode,omitempty"`
+	ToneCarrier float64 `json:"tone_carrier,omitempty"`
+	ToneBeat    float64 `json:"tone_beat,omitempty"`
+	ToneLevel   float64 `json:"tone_level"`
+
+	LimiterThreshold *float64 `json:"limiter_threshold,omitempty"`
+	LimiterRelease   *float64 `json:"limiter_release,omitempty"`
+	Compressor       *bool    `json:"compressor,omitempty"`
+
+	Tinnitus          bool    `json:"tinnitus,omitempty"`
+	TinnitusFrequency float64 `json:"tinnitus_frequency,omitempty"`
+	TinnitusWidth     float64 `json:"tinnitus_width,omitempty"`
+
+	EQ         []filter.Band      `json:"eq,omitempty"`
+	Modulation []mixer.Modulation `json:"modulation,omitempty"`
+
+	Evolve          bool               `json:"evolve,omitempty"`
+	EvolveBounds    mixer.EvolveBounds `json:"evolve_bounds"`
+	EvolveDriftTime float64            `json:"evolve_drift_time,omitempty"`
+
+	ReverbMix      float64  `json:"reverb_mix"`
+	ReverbRoomSize *float64 `json:"reverb_room_size,omitempty"`
+	ReverbDamping  *float64 `json:"reverb_damping,omitempty"`
+
+	Convolution     *bool  `json:"convolution,omitempty"`
+	ImpulseResponse string `json:"impulse_response,omitempty"`
+
+	Layers        []PersistedLayer `json:"layers,omitempty"`
+	CustomPresets []mqtt.Preset    `json:"custom_presets,omitempty"`
+}
+
+type PersistedLayer struct {
+	Sound     string  `json:"sound"`
+	ColorMode string  `json:"color_mode"`
+	Color     float64 `json:"color"`
+	Bass      float64 `json:"bass"`
+	Treble    float64 `json:"treble"`
+	Gain      float64 `json:"gain"`
+	Mute      bool    `json:"mute"`
+
+	RainDensity   float64 `json:"rain_density"`
+	RainIntensity float64 `json:"rain_intensity"`
+
+	OceanPeriod     float64 `json:"ocean_period"`
+	OceanDepth      float64 `json:"ocean_depth"`
+	OceanRandomness float64 `json:"ocean_randomness"`
+
+	ShushRate       float64 `json:"shush_rate"`
+	ShushDuty       float64 `json:"shush_duty"`
+	ShushRandomness float64 `json:"shush_randomness"`
+
+	GreyPhon float64 `json:"grey_phon,omitempty"`
+
+	Spectrum []noise.SpectrumPoint `json:"spectrum,omitempty"`
 }
 
 func main() {
 	cfg := config.Load()
 
-	m := mixer.NewMixer(cfg.SampleRate)
+	m := mixer.NewMixer(cfg.SampleRate, cfg.Layers)
+	m.SetTimerFade(time.Duration(cfg.SleepTimerFade) * time.Second)
+	m.SetFadeIn(cfg.PowerFadeIn)
+	m.SetFadeOut(cfg.PowerFadeOut)
+	m.SetBassFrequency(cfg.BassFrequency)
+	m.SetTrebleFrequency(cfg.TrebleFrequency)
+	m.SetShelfSlope(cfg.ShelfSlope)
+	m.SetTiltCenter(cfg.TiltCenter)
+	if err := m.SetImpulseResponse(cfg.ImpulseResponse); err != nil {
+		log.Printf("Failed to load impulse response: %v", err)
+	}
+	m.SetConvolution(cfg.Convolution)
+	m.SetLimiterThreshold(cfg.LimiterThreshold)
+	m.SetLimiterRelease(cfg.LimiterRelease)
+	m.SetCompressorThreshold(cfg.CompressorThreshold)
+	m.SetCompressor(cfg.Compressor)
 
 	restoreState(m, cfg.StateFile)
 
@@ -64,6 +151,7 @@ func main() {
 	<-sigChan
 
 	log.Println("Shutting down...")
+	m.Drain(time.Duration(m.GetFadeOut()*float64(time.Second)) + time.Second)
 }
 
 func reseedLoop(m *mixer.Mixer) {
@@ -94,6 +182,8 @@ func processCommands(m *mixer.Mixer, cmdChan <-chan mqtt.Command, mqttClient *mq
 	stateTicker := time.NewTicker(2 * time.Second)
 	defer stateTicker.Stop()
 
+	savedDeadline := m.GetSleepTimer()
+
 	for {
 		select {
 		case cmd, ok := <-cmdChan:
@@ -107,42 +197,294 @@ func processCommands(m *mixer.Mixer, cmdChan <-chan mqtt.Command, mqttClient *mq
 				m.SetPower(false)
 			case "set_volume":
 				m.SetMasterVolume(cmd.Value)
+			case "set_stereo_width":
+				m.SetStereoWidth(cmd.Value)
 			case "set_color":
-				m.SetColor(cmd.Value)
-				mqtt.CurrentPreset = "Custom"
+				m.SetLayerColor(cmd.Layer, cmd.Value)
+				if cmd.Layer == 0 {
+					mqtt.CurrentPreset = "Custom"
+				}
 			case "set_bass":
-				m.SetBass(cmd.Value)
-				mqtt.CurrentPreset = "Custom"
+				m.SetLayerBass(cmd.Layer, cmd.Value)
+				if cmd.Layer == 0 {
+					mqtt.CurrentPreset = "Custom"
+				}
 			case "set_treble":
-				m.SetTreble(cmd.Value)
-				mqtt.CurrentPreset = "Custom"
+				m.SetLayerTreble(cmd.Layer, cmd.Value)
+				if cmd.Layer == 0 {
+					mqtt.CurrentPreset = "Custom"
+				}
+			case "set_gain":
+				m.SetLayerGain(cmd.Layer, cmd.Value)
+			case "set_mute":
+				m.SetLayerMute(cmd.Layer, cmd.Value != 0)
+			case "set_sound":
+				m.SetLayerSound(cmd.Layer, cmd.Sound)
+				if cmd.Layer == 0 {
+					mqtt.CurrentPreset = "Custom"
+				}
+			case "set_color_mode":
+				m.SetLayerColorMode(cmd.Layer, cmd.Mode)
+			case "set_spectrum":
+				m.SetLayerSpectrum(cmd.Layer, cmd.Spectrum)
+				if cmd.Layer == 0 {
+					mqtt.CurrentPreset = "Custom"
+				}
+			case "set_rain_density":
+				m.SetLayerRainDensity(cmd.Layer, cmd.Value)
+			case "set_rain_intensity":
+				m.SetLayerRainIntensity(cmd.Layer, cmd.Value)
+			case "set_ocean_period":
+				m.SetLayerOceanPeriod(cmd.Layer, cmd.Value)
+			case "set_ocean_depth":
+				m.SetLayerOceanDepth(cmd.Layer, cmd.Value)
+			case "set_ocean_randomness":
+				m.SetLayerOceanRandomness(cmd.Layer, cmd.Value)
 			case "set_preset":
 				if p := mqtt.FindPreset(cmd.Preset); p != nil {
+					m.SetLayerSound(0, noise.ParseSound(string(p.Sound)))
 					m.SetColor(p.Color)
 					m.SetBass(p.Bass)
 					m.SetTreble(p.Treble)
+					m.SetTilt(p.Tilt)
+					m.SetHeartbeatLevel(p.Heartbeat)
+					if p.Spectrum != nil {
+						m.SetLayerSpectrum(0, p.Spectrum)
+					}
+					if p.BassFrequency != 0 {
+						m.SetBassFrequency(p.BassFrequency)
+					}
+					if p.TrebleFrequency != 0 {
+						m.SetTrebleFrequency(p.TrebleFrequency)
+					}
+					if p.ShelfSlope != 0 {
+						m.SetShelfSlope(p.ShelfSlope)
+					}
+					// Restart any drift from the preset's own settings
+					m.SetEvolve(false)
+					if p.EvolveDriftTime != 0 {
+						m.SetEvolveDriftTime(p.EvolveDriftTime)
+					}
+					if p.Evolve != nil {
+						m.SetEvolveBounds(*p.Evolve)
+						m.SetEvolve(true)
+					}
 					mqtt.CurrentPreset = p.Name
 				}
+			case "save_preset":
+				p := mqtt.Preset{
+					Name:      cmd.Preset,
+					Color:     m.GetColor(),
+					Bass:      m.GetBass(),
+					Treble:    m.GetTreble(),
+					Tilt:      m.GetTilt(),
+					Sound:     m.GetLayerSound(0),
+					Heartbeat: m.GetHeartbeatLevel(),
+
+					BassFrequency:   m.GetBassFrequency(),
+					TrebleFrequency: m.GetTrebleFrequency(),
+					ShelfSlope:      m.GetShelfSlope(),
+				}
+				if p.Sound == noise.SoundSpectrum {
+					p.Spectrum = m.GetLayerSpectrum(0)
+				}
+				if m.GetEvolve() {
+					bounds := m.GetEvolveBounds()
+					p.Evolve = &bounds
+					p.EvolveDriftTime = m.GetEvolveDriftTime()
+				}
+				if err := mqtt.SaveCustomPreset(p); err != nil {
+					log.Printf("Failed to save preset: %v", err)
+					break
+				}
+				mqtt.CurrentPreset = p.Name
+				mqttClient.PublishDiscovery()
+			case "delete_preset":
+				if mqtt.DeleteCustomPreset(cmd.Preset) {
+					if mqtt.CurrentPreset == cmd.Preset {
+						mqtt.CurrentPreset = "Custom"
+					}
+					mqttClient.PublishDiscovery()
+				}
+			case "set_timer":
+				if cmd.Value > 0 {
+					m.SetSleepTimer(time.Now().Add(time.Duration(cmd.Value * float64(time.Minute))))
+				} else {
+					m.SetSleepTimer(time.Time{})
+				}
+			case "set_shush_rate":
+				m.SetLayerShushRate(cmd.Layer, cmd.Value)
+			case "set_shush_duty":
+				m.SetLayerShushDuty(cmd.Layer, cmd.Value)
+			case "set_shush_randomness":
+				m.SetLayerShushRandomness(cmd.Layer, cmd.Value)
+			case "set_grey_phon":
+				m.SetLayerGreyPhon(cmd.Layer, cmd.Value)
+			case "set_heartbeat_bpm":
+				m.SetHeartbeatBPM(cmd.Value)
+			case "set_heartbeat_level":
+				m.SetHeartbeatLevel(cmd.Value)
+				mqtt.CurrentPreset = "Custom"
+			case "set_tone_mode":
+				m.SetToneMode(cmd.Tone)
+			case "set_tone_carrier":
+				m.SetToneCarrier(cmd.Value)
+			case "set_tone_beat":
+				m.SetToneBeat(cmd.Value)
+			case "set_tone_level":
+				m.SetToneLevel(cmd.Value)
+			case "set_limiter_threshold":
+				m.SetLimiterThreshold(cmd.Value)
+			case "set_limiter_release":
+				m.SetLimiterRelease(cmd.Value)
+			case "set_compressor":
+				m.SetCompressor(cmd.Value != 0)
+			case "set_tinnitus":
+				m.SetTinnitus(cmd.Value != 0)
+			case "set_tinnitus_frequency":
+				m.SetTinnitusFrequency(cmd.Value)
+			case "set_tinnitus_width":
+				m.SetTinnitusWidth(cmd.Value)
+			case "set_tilt":
+				m.SetTilt(cmd.Value)
+				mqtt.CurrentPreset = "Custom"
+			case "set_tilt_center":
+				m.SetTiltCenter(cmd.Value)
+			case "set_bass_frequency":
+				m.SetBassFrequency(cmd.Value)
+			case "set_treble_frequency":
+				m.SetTrebleFrequency(cmd.Value)
+			case "set_shelf_slope":
+				m.SetShelfSlope(cmd.Value)
+			case "set_eq":
+				m.SetEQ(cmd.EQ)
+			case "set_modulation":
+				m.SetModulations(cmd.Routings)
+			case "set_evolve":
+				m.SetEvolve(cmd.Value != 0)
+				mqtt.CurrentPreset = "Custom"
+			case "set_evolve_bounds":
+				m.SetEvolveBounds(cmd.Bounds)
+				mqtt.CurrentPreset = "Custom"
+			case "set_evolve_drift_time":
+				m.SetEvolveDriftTime(cmd.Value)
+			case "set_reverb_mix":
+				m.SetReverbMix(cmd.Value)
+			case "set_reverb_room_size":
+				m.SetReverbRoomSize(cmd.Value)
+			case "set_reverb_damping":
+				m.SetReverbDamping(cmd.Value)
+			case "set_convolution":
+				m.SetConvolution(cmd.Value != 0)
+			case "set_impulse_response":
+				if err := m.SetImpulseResponse(cmd.Path); err != nil {
+					log.Printf("Failed to load impulse response: %v", err)
+				}
+			case "set_fade_in":
+				m.SetFadeIn(cmd.Value)
+			case "set_fade_out":
+				m.SetFadeOut(cmd.Value)
 			case "stop_all":
 				m.SetPower(false)
 			}
 			saveState(m, stateFile)
+			savedDeadline = m.GetSleepTimer()
 			mqttClient.PublishState()
 		case <-stateTicker.C:
+			// The mixer powers off and clears the deadline on its own when
+			// the sleep timer expires; persist that so a restart stays off.
+			if deadline := m.GetSleepTimer(); !deadline.Equal(savedDeadline) {
+				saveState(m, stateFile)
+				savedDeadline = deadline
+			}
 			mqttClient.PublishState()
 		}
 	}
 }
 
 func saveState(m *mixer.Mixer, path string) {
+	fadeIn, fadeOut := m.GetFadeIn(), m.GetFadeOut()
+	threshold, release := m.GetLimiterThreshold(), m.GetLimiterRelease()
+	compressor, convolution := m.GetCompressor(), m.GetConvolution()
+	roomSize, damping := m.GetReverbRoomSize(), m.GetReverbDamping()
 	state := PersistedState{
 		MasterVolume: m.GetMasterVolume(),
 		Color:        m.GetColor(),
 		Bass:         m.GetBass(),
 		Treble:       m.GetTreble(),
 		Preset:       mqtt.CurrentPreset,
-		Power:        m.GetPower(),
+		Power:        m.GetTargetPower(),
+		StereoWidth:  m.GetStereoWidth(),
+		FadeIn:       &fadeIn,
+		FadeOut:      &fadeOut,
+
+		BassFrequency:   m.GetBassFrequency(),
+		TrebleFrequency: m.GetTrebleFrequency(),
+		ShelfSlope:      m.GetShelfSlope(),
+
+		Tilt:       m.GetTilt(),
+		TiltCenter: m.GetTiltCenter(),
+
+		HeartbeatBPM:   m.GetHeartbeatBPM(),
+		HeartbeatLevel: m.GetHeartbeatLevel(),
+
+		ToneMode:    string(m.GetToneMode()),
+		ToneCarrier: m.GetToneCarrier(),
+		ToneBeat:    m.GetToneBeat(),
+		ToneLevel:   m.GetToneLevel(),
+
+		LimiterThreshold: &threshold,
+		LimiterRelease:   &release,
+		Compressor:       &compressor,
+
+		Tinnitus:          m.GetTinnitus(),
+		TinnitusFrequency: m.GetTinnitusFrequency(),
+		TinnitusWidth:     m.GetTinnitusWidth(),
+
+		EQ:         m.GetEQ(),
+		Modulation: m.GetModulations(),
+
+		Evolve:          m.GetEvolve(),
+		EvolveBounds:    m.GetEvolveBounds(),
+		EvolveDriftTime: m.GetEvolveDriftTime(),
+
+		ReverbMix:      m.GetReverbMix(),
+		ReverbRoomSize: &roomSize,
+		ReverbDamping:  &damping,
+
+		Convolution:     &convolution,
+		ImpulseResponse: m.GetImpulseResponse(),
+	}
+	if deadline := m.GetSleepTimer(); !deadline.IsZero() {
+		state.TimerDeadline = deadline.Unix()
 	}
+	for n := range m.LayerCount() {
+		state.Layers = append(state.Layers, PersistedLayer{
+			Sound:     string(m.GetLayerSound(n)),
+			ColorMode: string(m.GetLayerColorMode(n)),
+			Color:     m.GetLayerColor(n),
+			Bass:      m.GetLayerBass(n),
+			Treble:    m.GetLayerTreble(n),
+			Gain:      m.GetLayerGain(n),
+			Mute:      m.GetLayerMute(n),
+
+			RainDensity:   m.GetLayerRainDensity(n),
+			RainIntensity: m.GetLayerRainIntensity(n),
+
+			OceanPeriod:     m.GetLayerOceanPeriod(n),
+			OceanDepth:      m.GetLayerOceanDepth(n),
+			OceanRandomness: m.GetLayerOceanRandomness(n),
+
+			ShushRate:       m.GetLayerShushRate(n),
+			ShushDuty:       m.GetLayerShushDuty(n),
+			ShushRandomness: m.GetLayerShushRandomness(n),
+
+			GreyPhon: m.GetLayerGreyPhon(n),
+
+			Spectrum: m.GetLayerSpectrum(n),
+		})
+	}
+	state.CustomPresets = mqtt.CustomPresets()
 
 	data, err := json.Marshal(state)
 	if err != nil {
@@ -169,11 +511,115 @@ func restoreState(m *mixer.Mixer, path string) {
 	}
 
 	m.SetMasterVolume(state.MasterVolume)
+	m.SetStereoWidth(state.StereoWidth)
 	m.SetColor(state.Color)
 	m.SetBass(state.Bass)
 	m.SetTreble(state.Treble)
+	if state.BassFrequency != 0 {
+		m.SetBassFrequency(state.BassFrequency)
+	}
+	if state.TrebleFrequency != 0 {
+		m.SetTrebleFrequency(state.TrebleFrequency)
+	}
+	if state.ShelfSlope != 0 {
+		m.SetShelfSlope(state.ShelfSlope)
+	}
+	m.SetTilt(state.Tilt)
+	if state.TiltCenter != 0 {
+		m.SetTiltCenter(state.TiltCenter)
+	}
 	m.SetPower(state.Power)
+	for n, layer := range state.Layers {
+		m.SetLayerSound(n, noise.ParseSound(layer.Sound))
+		m.SetLayerColorMode(n, noise.ParseColorMode(layer.ColorMode))
+		m.SetLayerRainDensity(n, layer.RainDensity)
+		m.SetLayerRainIntensity(n, layer.RainIntensity)
+		m.SetLayerOceanPeriod(n, layer.OceanPeriod)
+		m.SetLayerOceanDepth(n, layer.OceanDepth)
+		m.SetLayerOceanRandomness(n, layer.OceanRandomness)
+		m.SetLayerShushRate(n, layer.ShushRate)
+		m.SetLayerShushDuty(n, layer.ShushDuty)
+		m.SetLayerShushRandomness(n, layer.ShushRandomness)
+		m.SetLayerSpectrum(n, layer.Spectrum)
+		if layer.GreyPhon != 0 {
+			m.SetLayerGreyPhon(n, layer.GreyPhon)
+		}
+		m.SetLayerColor(n, layer.Color)
+		m.SetLayerBass(n, layer.Bass)
+		m.SetLayerTreble(n, layer.Treble)
+		m.SetLayerGain(n, layer.Gain)
+		m.SetLayerMute(n, layer.Mute)
+	}
+	if state.HeartbeatBPM != 0 {
+		m.SetHeartbeatBPM(state.HeartbeatBPM)
+	}
+	m.SetHeartbeatLevel(state.HeartbeatLevel)
+	m.SetToneMode(noise.ParseToneMode(state.ToneMode))
+	if state.ToneCarrier != 0 {
+		m.SetToneCarrier(state.ToneCarrier)
+	}
+	if state.ToneBeat != 0 {
+		m.SetToneBeat(state.ToneBeat)
+	}
+	m.SetToneLevel(state.ToneLevel)
+	if state.LimiterThreshold != nil {
+		m.SetLimiterThreshold(*state.LimiterThreshold)
+	}
+	if state.LimiterRelease != nil {
+		m.SetLimiterRelease(*state.LimiterRelease)
+	}
+	if state.Compressor != nil {
+		m.SetCompressor(*state.Compressor)
+	}
+	if state.TinnitusFrequency != 0 {
+		m.SetTinnitusFrequency(state.TinnitusFrequency)
+	}
+	if state.TinnitusWidth != 0 {
+		m.SetTinnitusWidth(state.TinnitusWidth)
+	}
+	m.SetTinnitus(state.Tinnitus)
+	m.SetEQ(state.EQ)
+	m.SetModulations(state.Modulation)
+	m.SetEvolveBounds(state.EvolveBounds)
+	if state.EvolveDriftTime != 0 {
+		m.SetEvolveDriftTime(state.EvolveDriftTime)
+	}
+	m.SetEvolve(state.Evolve)
+	m.SetReverbMix(state.ReverbMix)
+	if state.ReverbRoomSize != nil {
+		m.SetReverbRoomSize(*state.ReverbRoomSize)
+	}
+	if state.ReverbDamping != nil {
+		m.SetReverbDamping(*state.ReverbDamping)
+	}
+	if state.ImpulseResponse != "" {
+		if err := m.SetImpulseResponse(state.ImpulseResponse); err != nil {
+			log.Printf("Failed to load impulse response: %v", err)
+		}
+	}
+	if state.Convolution != nil {
+		m.SetConvolution(*state.Convolution)
+	}
+	if state.FadeIn != nil {
+		m.SetFadeIn(*state.FadeIn)
+	}
+	if state.FadeOut != nil {
+		m.SetFadeOut(*state.FadeOut)
+	}
+
+	if state.TimerDeadline != 0 {
+		deadline := time.Unix(state.TimerDeadline, 0)
+		if time.Now().Before(deadline) {
+			m.SetSleepTimer(deadline)
+			log.Printf("Resuming sleep timer: %s remaining", time.Until(deadline).Round(time.Second))
+		} else {
+			// The timer ran out while we were down
+			m.SetPower(false)
+			state.Power = false
+		}
+	}
 
+	mqtt.SetCustomPresets(state.CustomPresets)
 	if state.Preset != "" {
 		mqtt.CurrentPreset = state.Preset
 	}
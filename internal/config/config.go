@@ -16,6 +16,24 @@ type Config struct {
 	SampleRate   int
 	BufferSize   int
 	StateFile    string
+
+	SleepTimerFade int     // seconds
+	PowerFadeIn    float64 // seconds
+	PowerFadeOut   float64 // seconds
+	Layers         int
+
+	BassFrequency   float64 // Hz
+	TrebleFrequency float64 // Hz
+	ShelfSlope      float64
+	TiltCenter      float64 // Hz
+
+	LimiterThreshold    float64 // dBFS
+	LimiterRelease      float64 // ms
+	Compressor          bool
+	CompressorThreshold float64 // dBFS
+
+	ImpulseResponse string // room correction WAV or FIR coefficient file
+	Convolution     bool
 }
 
 func Load() *Config {
@@ -33,6 +51,24 @@ func Load() *Config {
 		SampleRate:   getEnvInt("SAMPLE_RATE", 44100),
 		BufferSize:   getEnvInt("BUFFER_SIZE", 2048),
 		StateFile:    getEnv("STATE_FILE", "/var/lib/pink-noise/state.json"),
+
+		SleepTimerFade: getEnvInt("SLEEP_TIMER_FADE", 60),
+		PowerFadeIn:    getEnvFloat("POWER_FADE_IN", 2),
+		PowerFadeOut:   getEnvFloat("POWER_FADE_OUT", 3),
+		Layers:         getEnvInt("LAYERS", 2),
+
+		BassFrequency:   getEnvFloat("BASS_FREQUENCY", 300),
+		TrebleFrequency: getEnvFloat("TREBLE_FREQUENCY", 3000),
+		ShelfSlope:      getEnvFloat("SHELF_SLOPE", 1),
+		TiltCenter:      getEnvFloat("TILT_CENTER", 1000),
+
+		LimiterThreshold:    getEnvFloat("LIMITER_THRESHOLD", -1),
+		LimiterRelease:      getEnvFloat("LIMITER_RELEASE", 100),
+		Compressor:          getEnvBool("COMPRESSOR", false),
+		CompressorThreshold: getEnvFloat("COMPRESSOR_THRESHOLD", -18),
+
+		ImpulseResponse: getEnv("IMPULSE_RESPONSE", ""),
+		Convolution:     getEnvBool("CONVOLUTION", false),
 	}
 
 	log.Printf("Config: MQTT=%s:%d, Topic=%s", cfg.MQTTBroker, cfg.MQTTPort, cfg.MQTTTopic)
@@ -54,3 +90,21 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
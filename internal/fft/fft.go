@@ -0,0 +1,59 @@
+// Package fft implements an in-place radix-2 fast Fourier transform.
+package fft
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// Forward replaces x with its discrete Fourier transform. len(x) must be a
+// power of two.
+func Forward(x []complex128) {
+	transform(x, -1)
+}
+
+// Inverse replaces x with its inverse discrete Fourier transform, scaled by
+// 1/len(x) so Inverse(Forward(x)) returns x. len(x) must be a power of two.
+func Inverse(x []complex128) {
+	transform(x, 1)
+	scale := complex(1/float64(len(x)), 0)
+	for i := range x {
+		x[i] *= scale
+	}
+}
+
+func transform(x []complex128, sign float64) {
+	n := len(x)
+	if n <= 1 {
+		return
+	}
+	if n&(n-1) != 0 {
+		panic("fft: length is not a power of two")
+	}
+
+	// Bit-reversal permutation
+	shift := 64 - bits.TrailingZeros(uint(n))
+	for i := range n {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if j > i {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	// Iterative Cooley-Tukey butterflies
+	for size := 2; size <= n; size <<= 1 {
+		half := size / 2
+		step := cmplx.Exp(complex(0, sign*2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := range half {
+				a := x[start+k]
+				b := x[start+k+half] * w
+				x[start+k] = a + b
+				x[start+k+half] = a - b
+				w *= step
+			}
+		}
+	}
+}
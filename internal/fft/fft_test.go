@@ -0,0 +1,45 @@
+package fft
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+func TestForwardMatchesDFT(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 4, 8, 64, 1024} {
+		x := make([]complex128, n)
+		for i := range x {
+			x[i] = complex(rng.NormFloat64(), rng.NormFloat64())
+		}
+		got := append([]complex128(nil), x...)
+		Forward(got)
+		for k := range n {
+			var want complex128
+			for i, v := range x {
+				want += v * cmplx.Exp(complex(0, -2*math.Pi*float64(i*k)/float64(n)))
+			}
+			if cmplx.Abs(got[k]-want) > 1e-9*float64(n) {
+				t.Fatalf("n=%d bin %d: got %v, want %v", n, k, got[k], want)
+			}
+		}
+	}
+}
+
+func TestInverseUndoesForward(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	x := make([]complex128, 512)
+	for i := range x {
+		x[i] = complex(rng.NormFloat64(), rng.NormFloat64())
+	}
+	y := append([]complex128(nil), x...)
+	Forward(y)
+	Inverse(y)
+	for i := range x {
+		if cmplx.Abs(y[i]-x[i]) > 1e-12 {
+			t.Fatalf("sample %d: got %v, want %v", i, y[i], x[i])
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package filter
+
+import (
+	"errors"
+	"math"
+)
+
+// Band describes one band of a parametric EQ.
+type Band struct {
+	Type Type    `json:"type"`
+	Freq float64 `json:"freq"` // Hz
+	Q    float64 `json:"q"`    // slope S for shelf bands
+	Gain float64 `json:"gain"` // dB, peaking and shelf bands only
+}
+
+// Validate reports an error for a band without a valid type.
+func (b Band) Validate() error {
+	if !b.Type.Valid() {
+		return errors.New("band has no valid type")
+	}
+	return nil
+}
+
+// Clamp returns the band with its parameters limited to usable ranges:
+// 20 Hz up to 0.45 of the sample rate, Q 0.1-20 (default 0.707), or slope
+// 0.1-1 (default 1) for shelves, and gain ±24 dB.
+func (b Band) Clamp(sampleRate float64) Band {
+	b.Freq = math.Max(20, math.Min(sampleRate*0.45, b.Freq))
+	switch b.Type {
+	case LowShelf, HighShelf:
+		if b.Q == 0 {
+			b.Q = 1
+		}
+		b.Q = math.Max(0.1, math.Min(1, b.Q))
+	default:
+		if b.Q == 0 {
+			b.Q = math.Sqrt2 / 2
+		}
+		b.Q = math.Max(0.1, math.Min(20, b.Q))
+	}
+	b.Gain = math.Max(-24, math.Min(24, b.Gain))
+	return b
+}
+
+// NewBand returns a biquad for the band.
+func NewBand(band Band, sampleRate float64) *Biquad {
+	return New(band.Type, band.Freq, band.Q, band.Gain, sampleRate)
+}
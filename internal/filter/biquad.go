@@ -1,52 +1,142 @@
 package filter
 
-import "math"
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
 
-type ShelfType int
+// Type is a biquad response. The zero Type is invalid, so a band whose type
+// was left out is caught rather than becoming a low shelf.
+type Type int
 
 const (
-	LowShelf ShelfType = iota
+	LowShelf Type = iota + 1
 	HighShelf
+	Peaking
+	BandStop // a single-biquad notch; see Notch for the wide band-stop
+	LowPass
+	HighPass
+	BandPass
 )
 
-type Biquad struct {
+var typeNames = map[Type]string{
+	LowShelf:  "lowshelf",
+	HighShelf: "highshelf",
+	Peaking:   "peaking",
+	BandStop:  "notch",
+	LowPass:   "lowpass",
+	HighPass:  "highpass",
+	BandPass:  "bandpass",
+}
+
+func (t Type) String() string {
+	return typeNames[t]
+}
+
+// Valid reports whether t is one of the filter types.
+func (t Type) Valid() bool {
+	_, ok := typeNames[t]
+	return ok
+}
+
+// MarshalText encodes a Type by name, e.g. "peaking".
+func (t Type) MarshalText() ([]byte, error) {
+	name, ok := typeNames[t]
+	if !ok {
+		return nil, fmt.Errorf("unknown filter type %d", int(t))
+	}
+	return []byte(name), nil
+}
+
+func (t *Type) UnmarshalText(text []byte) error {
+	for typ, name := range typeNames {
+		if name == string(text) {
+			*t = typ
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown filter type %q", text)
+}
+
+// rampTime is the time constant in seconds over which a biquad glides to
+// new coefficients, so dragging a slider doesn't cause zipper noise.
+const rampTime = 0.02
+
+// coefficients are normalized biquad coefficients (a0 = 1).
+type coefficients struct {
 	b0, b1, b2 float64
 	a1, a2     float64
+}
+
+type Biquad struct {
+	// coefficients in use, gliding towards target while ramping
+	coefficients
+	target  coefficients
+	ramping bool
+	primed  bool
 
 	x1, x2 float64
 	y1, y2 float64
 
-	shelfType  ShelfType
+	filterType Type
 	f0         float64
+	q          float64
+	gainDB     float64
 	sampleRate float64
 }
 
-func NewShelf(shelfType ShelfType, f0, gainDB, sampleRate float64) *Biquad {
+// NewShelf returns a shelf with slope S=1, the steepest slope that stays
+// free of overshoot.
+func NewShelf(shelfType Type, f0, gainDB, sampleRate float64) *Biquad {
+	return New(shelfType, f0, 1, gainDB, sampleRate)
+}
+
+// New returns a biquad of any type. q is the quality factor for peaking,
+// notch, pass and band-pass filters, and the slope S (0-1] for shelves;
+// gainDB only applies to peaking filters and shelves.
+func New(filterType Type, f0, q, gainDB, sampleRate float64) *Biquad {
 	b := &Biquad{
-		shelfType:  shelfType,
-		f0:         f0,
+		filterType: filterType,
 		sampleRate: sampleRate,
 	}
-	b.computeCoefficients(gainDB)
+	b.Set(f0, q, gainDB)
 	return b
 }
 
 func (b *Biquad) UpdateGain(gainDB float64) {
-	b.computeCoefficients(gainDB)
+	b.Set(b.f0, b.q, gainDB)
 }
 
-// computeCoefficients uses Robert Bristow-Johnson Audio EQ Cookbook formulas with S=1.
-func (b *Biquad) computeCoefficients(gainDB float64) {
-	A := math.Pow(10, gainDB/40.0)
+// Set changes the frequency, Q and gain, keeping the filter state. The
+// response glides to the new settings over a few tens of milliseconds.
+func (b *Biquad) Set(f0, q, gainDB float64) {
+	b.f0 = f0
+	b.q = q
+	b.gainDB = gainDB
+	b.computeCoefficients()
+}
+
+// computeCoefficients uses Robert Bristow-Johnson Audio EQ Cookbook formulas.
+func (b *Biquad) computeCoefficients() {
+	A := math.Pow(10, b.gainDB/40.0)
 	w0 := 2 * math.Pi * b.f0 / b.sampleRate
 	cosw0 := math.Cos(w0)
 	sinw0 := math.Sin(w0)
-	// S=1 slope: alpha = sin(w0)/2 * sqrt(2)
-	alpha := sinw0 / 2 * math.Sqrt2
+
+	var alpha float64
+	switch b.filterType {
+	case LowShelf, HighShelf:
+		// Shelf slope S; S=1 gives alpha = sin(w0)/2 * sqrt(2)
+		slope := math.Max(0.01, math.Min(1, b.q))
+		alpha = sinw0 / 2 * math.Sqrt((A+1/A)*(1/slope-1)+2)
+	default:
+		alpha = sinw0 / (2 * b.q)
+	}
 
 	var b0, b1, b2, a0, a1, a2 float64
 
-	switch b.shelfType {
+	switch b.filterType {
 	case LowShelf:
 		twoSqrtAAlpha := 2 * math.Sqrt(A) * alpha
 		b0 = A * ((A + 1) - (A-1)*cosw0 + twoSqrtAAlpha)
@@ -63,16 +153,64 @@ func (b *Biquad) computeCoefficients(gainDB float64) {
 		a0 = (A + 1) - (A-1)*cosw0 + twoSqrtAAlpha
 		a1 = 2 * ((A - 1) - (A+1)*cosw0)
 		a2 = (A + 1) - (A-1)*cosw0 - twoSqrtAAlpha
+	case Peaking:
+		b0 = 1 + alpha*A
+		b1 = -2 * cosw0
+		b2 = 1 - alpha*A
+		a0 = 1 + alpha/A
+		a1 = -2 * cosw0
+		a2 = 1 - alpha/A
+	case BandStop:
+		b0 = 1
+		b1 = -2 * cosw0
+		b2 = 1
+		a0 = 1 + alpha
+		a1 = -2 * cosw0
+		a2 = 1 - alpha
+	case LowPass:
+		b0 = (1 - cosw0) / 2
+		b1 = 1 - cosw0
+		b2 = (1 - cosw0) / 2
+		a0 = 1 + alpha
+		a1 = -2 * cosw0
+		a2 = 1 - alpha
+	case HighPass:
+		b0 = (1 + cosw0) / 2
+		b1 = -(1 + cosw0)
+		b2 = (1 + cosw0) / 2
+		a0 = 1 + alpha
+		a1 = -2 * cosw0
+		a2 = 1 - alpha
+	case BandPass:
+		// Constant 0 dB peak gain
+		b0 = alpha
+		b1 = 0
+		b2 = -alpha
+		a0 = 1 + alpha
+		a1 = -2 * cosw0
+		a2 = 1 - alpha
 	}
 
-	b.b0 = b0 / a0
-	b.b1 = b1 / a0
-	b.b2 = b2 / a0
-	b.a1 = a1 / a0
-	b.a2 = a2 / a0
+	b.setCoefficients(coefficients{b0 / a0, b1 / a0, b2 / a0, a1 / a0, a2 / a0})
+}
+
+// setCoefficients sets the coefficients to glide to. The first call takes
+// effect immediately.
+func (b *Biquad) setCoefficients(c coefficients) {
+	b.target = c
+	if !b.primed {
+		b.coefficients = c
+		b.primed = true
+		return
+	}
+	b.ramping = b.coefficients != c
 }
 
 func (b *Biquad) Process(samples []float64) {
+	if b.ramping {
+		b.processRamp(samples)
+		return
+	}
 	for i, x := range samples {
 		y := b.b0*x + b.b1*b.x1 + b.b2*b.x2 - b.a1*b.y1 - b.a2*b.y2
 		b.x2 = b.x1
@@ -82,3 +220,53 @@ func (b *Biquad) Process(samples []float64) {
 		samples[i] = y
 	}
 }
+
+// processRamp filters while moving the coefficients a step towards the
+// target every sample. Each step is a convex combination of two stable
+// filters, which is itself stable.
+func (b *Biquad) processRamp(samples []float64) {
+	k := 1 - math.Exp(-1/(rampTime*b.sampleRate))
+	c, t := &b.coefficients, &b.target
+	for i, x := range samples {
+		c.b0 += (t.b0 - c.b0) * k
+		c.b1 += (t.b1 - c.b1) * k
+		c.b2 += (t.b2 - c.b2) * k
+		c.a1 += (t.a1 - c.a1) * k
+		c.a2 += (t.a2 - c.a2) * k
+
+		y := c.b0*x + c.b1*b.x1 + c.b2*b.x2 - c.a1*b.y1 - c.a2*b.y2
+		b.x2 = b.x1
+		b.x1 = x
+		b.y2 = b.y1
+		b.y1 = y
+		samples[i] = y
+	}
+
+	diff := math.Max(math.Abs(t.b0-c.b0), math.Abs(t.b1-c.b1))
+	diff = math.Max(diff, math.Max(math.Abs(t.b2-c.b2), math.Abs(t.a1-c.a1)))
+	diff = math.Max(diff, math.Abs(t.a2-c.a2))
+	if diff < 1e-9 {
+		*c = *t
+		b.ramping = false
+	}
+}
+
+// Response returns the magnitude response |H(f)| at f Hz, for the
+// coefficients the filter is settling to.
+func (b *Biquad) Response(f float64) float64 {
+	zInv := cmplx.Exp(complex(0, -2*math.Pi*f/b.sampleRate))
+	c := b.target
+	h := (complex(c.b0, 0) + complex(c.b1, 0)*zInv + complex(c.b2, 0)*zInv*zInv) /
+		(1 + complex(c.a1, 0)*zInv + complex(c.a2, 0)*zInv*zInv)
+	return cmplx.Abs(h)
+}
+
+// CascadeResponse returns the magnitude response at f Hz of biquads applied
+// one after another.
+func CascadeResponse(cascade []*Biquad, f float64) float64 {
+	mag := 1.0
+	for _, b := range cascade {
+		mag *= b.Response(f)
+	}
+	return mag
+}
@@ -0,0 +1,142 @@
+package filter
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/agusx1211/pink-noise/internal/fft"
+)
+
+// convolverBlock is the partition size of the convolver in samples. It sets
+// the latency: output lags input by one block.
+const convolverBlock = 512
+
+// responseSize is the smallest FFT size the magnitude response is sampled
+// with, fine enough to resolve a few Hz.
+const responseSize = 8192
+
+// Convolver convolves a signal with an impulse response by uniformly
+// partitioned overlap-save FFT convolution. The impulse response is split
+// into blocks whose spectra are multiplied with the spectra of recent input
+// blocks, so the cost per sample grows with the length of the response
+// divided by the block size rather than with the length itself.
+type Convolver struct {
+	sampleRate float64
+	magnitude  []float64 // |H| at bins up to Nyquist, for Response
+
+	partitions [][]complex128 // spectrum of each impulse response block
+	history    [][]complex128 // spectra of recent input blocks, a ring
+	newest     int            // index of the newest block in history
+
+	input  []float64 // previous and current input block
+	output []float64 // current output block
+	pos    int
+	work   []complex128
+}
+
+func NewConvolver(ir []float64, sampleRate float64) *Convolver {
+	n := 2 * convolverBlock
+	count := max(1, (len(ir)+convolverBlock-1)/convolverBlock)
+	c := &Convolver{
+		sampleRate: sampleRate,
+		partitions: make([][]complex128, count),
+		history:    make([][]complex128, count),
+		input:      make([]float64, n),
+		output:     make([]float64, convolverBlock),
+		work:       make([]complex128, n),
+	}
+	for p := range c.partitions {
+		spectrum := make([]complex128, n)
+		for i := range convolverBlock {
+			if k := p*convolverBlock + i; k < len(ir) {
+				spectrum[i] = complex(ir[k], 0)
+			}
+		}
+		fft.Forward(spectrum)
+		c.partitions[p] = spectrum
+		c.history[p] = make([]complex128, n)
+	}
+	c.magnitude = magnitudeResponse(ir)
+	return c
+}
+
+// magnitudeResponse returns the magnitude spectrum of ir from 0 Hz to
+// Nyquist, zero-padded to at least four times its length so it's smooth
+// enough to interpolate.
+func magnitudeResponse(ir []float64) []float64 {
+	n := responseSize
+	for n < 4*len(ir) {
+		n *= 2
+	}
+	spectrum := make([]complex128, n)
+	for i, v := range ir {
+		spectrum[i] = complex(v, 0)
+	}
+	fft.Forward(spectrum)
+	magnitude := make([]float64, n/2+1)
+	for i := range magnitude {
+		magnitude[i] = cmplx.Abs(spectrum[i])
+	}
+	return magnitude
+}
+
+// Response returns the magnitude response |H(f)| at f Hz of the impulse
+// response, interpolated from its spectrum computed when it was loaded.
+func (c *Convolver) Response(f float64) float64 {
+	last := len(c.magnitude) - 1
+	pos := math.Max(0, math.Min(float64(last), 2*float64(last)*f/c.sampleRate))
+	i := min(int(pos), last-1)
+	frac := pos - float64(i)
+	return c.magnitude[i]*(1-frac) + c.magnitude[i+1]*frac
+}
+
+// Reset clears the filter state, as if the input had been silent.
+func (c *Convolver) Reset() {
+	for _, h := range c.history {
+		clear(h)
+	}
+	clear(c.input)
+	clear(c.output)
+	c.pos = 0
+}
+
+// Process convolves samples in place, delayed by convolverBlock samples.
+func (c *Convolver) Process(samples []float64) {
+	for i, x := range samples {
+		c.input[convolverBlock+c.pos] = x
+		samples[i] = c.output[c.pos]
+		c.pos++
+		if c.pos == convolverBlock {
+			c.processBlock()
+			c.pos = 0
+		}
+	}
+}
+
+// processBlock filters the input block that just filled up.
+func (c *Convolver) processBlock() {
+	count := len(c.partitions)
+	c.newest = (c.newest + count - 1) % count
+	spectrum := c.history[c.newest]
+	for i, x := range c.input {
+		spectrum[i] = complex(x, 0)
+	}
+	fft.Forward(spectrum)
+
+	// Partition p meets the input block from p blocks ago
+	clear(c.work)
+	for p, h := range c.partitions {
+		x := c.history[(c.newest+p)%count]
+		for i := range c.work {
+			c.work[i] += x[i] * h[i]
+		}
+	}
+	fft.Inverse(c.work)
+
+	// Overlap-save: the first half is circular wrap-around, the second half
+	// is the output
+	for i := range c.output {
+		c.output[i] = real(c.work[convolverBlock+i])
+	}
+	copy(c.input, c.input[convolverBlock:])
+}
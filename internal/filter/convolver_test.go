@@ -0,0 +1,91 @@
+package filter
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// directConvolve returns x convolved with ir, truncated to len(x).
+func directConvolve(x, ir []float64) []float64 {
+	y := make([]float64, len(x))
+	for n := range y {
+		for k := 0; k < len(ir) && k <= n; k++ {
+			y[n] += ir[k] * x[n-k]
+		}
+	}
+	return y
+}
+
+func TestConvolverMatchesDirectConvolution(t *testing.T) {
+	tests := []struct {
+		name     string
+		irLength int
+		chunk    int // samples per Process call
+	}{
+		{"single tap", 1, 256},
+		{"shorter than a block", 100, 333},
+		{"one block less one", convolverBlock - 1, 1000},
+		{"exactly one block", convolverBlock, convolverBlock},
+		{"one block plus one", convolverBlock + 1, 77},
+		{"several blocks", 3*convolverBlock + 17, 1024},
+		{"sample by sample", 2*convolverBlock + 5, 1},
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ir := make([]float64, tc.irLength)
+			for i := range ir {
+				ir[i] = rng.NormFloat64() * math.Exp(-float64(i)/float64(tc.irLength))
+			}
+			x := make([]float64, 6*convolverBlock+123)
+			for i := range x {
+				x[i] = rng.Float64()*2 - 1
+			}
+			want := directConvolve(x, ir)
+
+			c := NewConvolver(ir, 44100)
+			got := append([]float64(nil), x...)
+			for off := 0; off < len(got); off += tc.chunk {
+				c.Process(got[off:min(off+tc.chunk, len(got))])
+			}
+
+			// The output lags the input by one block
+			for i := convolverBlock; i < len(got); i++ {
+				if d := math.Abs(got[i] - want[i-convolverBlock]); d > 1e-9 {
+					t.Fatalf("sample %d: got %g, want %g", i, got[i], want[i-convolverBlock])
+				}
+			}
+			for i := range convolverBlock {
+				if got[i] != 0 {
+					t.Fatalf("sample %d during the latency: got %g, want 0", i, got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestConvolverResponse(t *testing.T) {
+	const sampleRate = 48000.0
+	tests := []struct {
+		name string
+		ir   []float64
+		want func(f float64) float64
+	}{
+		{"gain", []float64{0.5}, func(f float64) float64 { return 0.5 }},
+		{"delay", []float64{0, 0, 0, 1}, func(f float64) float64 { return 1 }},
+		{"two-tap average", []float64{0.5, 0.5}, func(f float64) float64 {
+			return math.Abs(math.Cos(math.Pi * f / sampleRate))
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewConvolver(tc.ir, sampleRate)
+			for _, f := range []float64{20, 100, 1000, 5000, 12345, 20000} {
+				if got, want := c.Response(f), tc.want(f); math.Abs(got-want) > 1e-4 {
+					t.Errorf("at %g Hz: got %g, want %g", f, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package filter
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/agusx1211/pink-noise/internal/wav"
+)
+
+// maxImpulseSeconds caps how much of an impulse response is used. Room
+// correction filters are far shorter; this only bounds the CPU and memory
+// cost of a wrong file.
+const maxImpulseSeconds = 2
+
+// LoadImpulseResponse reads an impulse response from a WAV file, or from a
+// text file of FIR coefficients separated by whitespace or commas. It
+// returns one slice per channel. A WAV file must be recorded at sampleRate
+// and is rejected if longer than maxImpulseSeconds; coefficients are
+// truncated.
+func LoadImpulseResponse(path string, sampleRate int) ([][]float64, error) {
+	var channels [][]float64
+	if strings.EqualFold(filepath.Ext(path), ".wav") {
+		audio, err := wav.Load(path, maxImpulseSeconds*sampleRate)
+		if err != nil {
+			return nil, err
+		}
+		if audio.SampleRate != sampleRate {
+			return nil, fmt.Errorf("impulse response is %d Hz, expected %d Hz", audio.SampleRate, sampleRate)
+		}
+		channels = audio.Channels
+	} else {
+		coeffs, err := loadCoefficients(path)
+		if err != nil {
+			return nil, err
+		}
+		channels = [][]float64{coeffs}
+	}
+
+	if len(channels) == 0 || len(channels[0]) == 0 {
+		return nil, fmt.Errorf("impulse response %s is empty", path)
+	}
+	limit := maxImpulseSeconds * sampleRate
+	for ch, ir := range channels {
+		if len(ir) > limit {
+			log.Printf("Truncating impulse response %s to %d seconds", path, maxImpulseSeconds)
+			channels[ch] = ir[:limit]
+		}
+	}
+	return channels, nil
+}
+
+func loadCoefficients(path string) ([]float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.FieldsFunc(string(data), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	coeffs := make([]float64, 0, len(fields))
+	for _, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FIR coefficient %q", field)
+		}
+		coeffs = append(coeffs, v)
+	}
+	return coeffs, nil
+}
@@ -0,0 +1,187 @@
+package filter
+
+import "math"
+
+// Limiter is a stereo-linked look-ahead peak limiter with an optional gentle
+// compressor in front of it. Audio is delayed by the look-ahead so gain can
+// be pulled down smoothly before a peak arrives instead of clipping it.
+type Limiter struct {
+	sampleRate float64
+	lookahead  int
+
+	threshold float64 // linear
+	release   float64 // per-sample smoothing coefficient
+
+	// Look-ahead delay lines
+	delayL, delayR []float64
+	pos            int
+
+	// Sliding minimum of the required gain over the look-ahead window,
+	// kept as a monotonic deque of (index, gain) in a ring buffer
+	minIdx  []int
+	minGain []float64
+	minHead int
+	minLen  int
+	n       int
+
+	// Moving average of the held minimum, for a smooth attack
+	avgBuf []float64
+	avgSum float64
+
+	gain float64
+
+	compressor    bool
+	compThreshold float64 // dB
+	compRatio     float64
+	compEnv       float64 // linear peak envelope
+	compAttack    float64
+	compRelease   float64
+
+	gainReduction float64 // dB, largest in the last Process call
+}
+
+func NewLimiter(sampleRate float64) *Limiter {
+	lookahead := int(0.005 * sampleRate) // 5 ms
+	l := &Limiter{
+		sampleRate:    sampleRate,
+		lookahead:     lookahead,
+		delayL:        make([]float64, lookahead),
+		delayR:        make([]float64, lookahead),
+		minIdx:        make([]int, lookahead+1),
+		minGain:       make([]float64, lookahead+1),
+		avgBuf:        make([]float64, lookahead),
+		avgSum:        float64(lookahead),
+		gain:          1,
+		compThreshold: -18,
+		compRatio:     2,
+		compAttack:    timeCoefficient(0.010, sampleRate),
+		compRelease:   timeCoefficient(0.200, sampleRate),
+	}
+	for i := range l.avgBuf {
+		l.avgBuf[i] = 1
+	}
+	l.SetThreshold(-1)
+	l.SetRelease(100)
+	return l
+}
+
+// timeCoefficient returns the one-pole smoothing coefficient for a time constant.
+func timeCoefficient(seconds, sampleRate float64) float64 {
+	return 1 - math.Exp(-1/(seconds*sampleRate))
+}
+
+// SetThreshold sets the limiter ceiling in dBFS.
+func (l *Limiter) SetThreshold(dB float64) {
+	l.threshold = math.Pow(10, math.Min(0, dB)/20)
+}
+
+// SetRelease sets how long gain takes to recover after a peak, in milliseconds.
+func (l *Limiter) SetRelease(ms float64) {
+	l.release = timeCoefficient(math.Max(1, ms)/1000, l.sampleRate)
+}
+
+// SetCompressor enables or disables the compressor and sets its threshold
+// in dBFS. The ratio is a fixed, gentle 2:1.
+func (l *Limiter) SetCompressor(enabled bool, thresholdDB float64) {
+	l.compressor = enabled
+	l.compThreshold = thresholdDB
+}
+
+// GainReduction returns the largest gain reduction applied during the last
+// Process call, in dB (0 when idle, negative when working).
+func (l *Limiter) GainReduction() float64 {
+	return l.gainReduction
+}
+
+func (l *Limiter) Process(left, right []float64) {
+	minGain := 1.0
+
+	for i := range left {
+		x := left[i]
+		y := right[i]
+
+		if l.compressor {
+			g := l.compress(math.Max(math.Abs(x), math.Abs(y)))
+			x *= g
+			y *= g
+		}
+
+		// Gain needed for this sample to stay under the ceiling
+		peak := math.Max(math.Abs(x), math.Abs(y))
+		required := 1.0
+		if peak > l.threshold {
+			required = l.threshold / peak
+		}
+
+		held := l.pushMin(required)
+
+		// Average the held minimum over the look-ahead so gain ramps down
+		// just in time for the delayed peak
+		avgPos := l.n % l.lookahead
+		l.avgSum += held - l.avgBuf[avgPos]
+		l.avgBuf[avgPos] = held
+		target := l.avgSum / float64(l.lookahead)
+
+		if target < l.gain {
+			l.gain = target
+		} else {
+			l.gain += (target - l.gain) * l.release
+		}
+
+		outL := l.delayL[l.pos]
+		outR := l.delayR[l.pos]
+		l.delayL[l.pos] = x
+		l.delayR[l.pos] = y
+		l.pos = (l.pos + 1) % l.lookahead
+
+		left[i] = outL * l.gain
+		right[i] = outR * l.gain
+		minGain = math.Min(minGain, l.gain)
+		l.n++
+	}
+
+	l.gainReduction = 20 * math.Log10(minGain)
+}
+
+// pushMin adds a required gain to the sliding window and returns the window minimum.
+func (l *Limiter) pushMin(g float64) float64 {
+	size := len(l.minIdx)
+
+	// Expire the oldest entry once it leaves the window
+	if l.minLen > 0 && l.n-l.minIdx[l.minHead] >= l.lookahead+1 {
+		l.minHead = (l.minHead + 1) % size
+		l.minLen--
+	}
+
+	// Drop entries that can never be the minimum again
+	for l.minLen > 0 {
+		last := (l.minHead + l.minLen - 1) % size
+		if l.minGain[last] < g {
+			break
+		}
+		l.minLen--
+	}
+	tail := (l.minHead + l.minLen) % size
+	l.minIdx[tail] = l.n
+	l.minGain[tail] = g
+	l.minLen++
+
+	return l.minGain[l.minHead]
+}
+
+// compress returns the compressor gain for the given stereo peak level.
+func (l *Limiter) compress(peak float64) float64 {
+	if peak > l.compEnv {
+		l.compEnv += (peak - l.compEnv) * l.compAttack
+	} else {
+		l.compEnv += (peak - l.compEnv) * l.compRelease
+	}
+	if l.compEnv <= 0 {
+		return 1
+	}
+	over := 20*math.Log10(l.compEnv) - l.compThreshold
+	if over <= 0 {
+		return 1
+	}
+	return math.Pow(10, -over*(1-1/l.compRatio)/20)
+}
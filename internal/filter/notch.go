@@ -0,0 +1,76 @@
+package filter
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// notchOrder is the order of the Butterworth low-pass prototype. The
+// band-stop has twice the order, built from notchOrder biquads, which gives
+// about 35 dB of rejection halfway between the center and either edge.
+const notchOrder = 6
+
+// Notch is a band-stop filter that removes a band a given number of octaves
+// wide around a center frequency. It is a Butterworth band-stop, so the
+// response is flat outside the band and -3 dB at its edges.
+type Notch struct {
+	sections   []*Biquad
+	sampleRate float64
+}
+
+func NewNotch(center, width, sampleRate float64) *Notch {
+	n := &Notch{sampleRate: sampleRate}
+	for range notchOrder {
+		n.sections = append(n.sections, &Biquad{sampleRate: sampleRate})
+	}
+	n.Set(center, width)
+	return n
+}
+
+// Set moves the notch to a new center frequency in Hz and width in octaves,
+// keeping the filter state so playback continues without a gap. Like a
+// Biquad, it glides to the new band rather than jumping.
+func (n *Notch) Set(center, width float64) {
+	// Prewarped band edges; the top edge is kept clear of Nyquist
+	k := 2 * n.sampleRate
+	warp := func(f float64) float64 {
+		return k * math.Tan(math.Pi*math.Min(f, n.sampleRate*0.45)/n.sampleRate)
+	}
+	lo := warp(center * math.Pow(2, -width/2))
+	hi := warp(center * math.Pow(2, width/2))
+	w0 := math.Sqrt(lo * hi)
+	bw := hi - lo
+
+	// Every section places its zeros on the (digital) center frequency
+	b1 := -2 * math.Cos(2*math.Atan(w0/k))
+
+	// Each prototype pole p maps to the two band-stop poles solving
+	// s² - (bw/p)s + w0² = 0. Taking the upper half of the prototype poles,
+	// each band-stop pole and its conjugate form one biquad.
+	for i := range notchOrder / 2 {
+		p := cmplx.Exp(complex(0, math.Pi*float64(2*i+notchOrder+1)/float64(2*notchOrder)))
+		h := complex(bw, 0) / (2 * p)
+		d := cmplx.Sqrt(h*h - complex(w0*w0, 0))
+		for j, s := range []complex128{h + d, h - d} {
+			z := (complex(k, 0) + s) / (complex(k, 0) - s)
+			a1 := -2 * real(z)
+			a2 := real(z)*real(z) + imag(z)*imag(z)
+
+			// Unity gain at DC
+			g := (1 + a1 + a2) / (2 + b1)
+
+			n.sections[2*i+j].setCoefficients(coefficients{g, g * b1, g, a1, a2})
+		}
+	}
+}
+
+func (n *Notch) Process(samples []float64) {
+	for _, b := range n.sections {
+		b.Process(samples)
+	}
+}
+
+// Response returns the magnitude response |H(f)| at f Hz.
+func (n *Notch) Response(f float64) float64 {
+	return CascadeResponse(n.sections, f)
+}
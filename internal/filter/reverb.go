@@ -0,0 +1,120 @@
+package filter
+
+import "math"
+
+// Freeverb tunings: comb and all-pass delay lengths in samples at 44.1 kHz,
+// mutually prime so their echoes don't pile up, and the offset of the right
+// channel's delays that decorrelates it from the left.
+var (
+	reverbCombTuning    = []int{1116, 1188, 1277, 1356, 1422, 1491, 1557, 1617}
+	reverbAllpassTuning = []int{556, 441, 341, 225}
+)
+
+const (
+	reverbStereoSpread = 23
+	reverbTuningRate   = 44100.0
+
+	// Freeverb's fixed input gain, which keeps the sum of the eight
+	// resonant combs in range, and its wet scale. Together they bring the
+	// wet output to about the level of the input. The filters are linear, so
+	// both are applied to the output.
+	reverbInputGain = 0.015
+	reverbWetScale  = 3
+)
+
+// comb is a feedback comb filter with a one-pole low-pass in the loop, which
+// makes high frequencies die away faster than low ones.
+type comb struct {
+	buf      []float64
+	pos      int
+	store    float64
+	feedback float64
+	damp     float64
+}
+
+func (c *comb) process(x float64) float64 {
+	out := c.buf[c.pos]
+	c.store = out*(1-c.damp) + c.store*c.damp
+	c.buf[c.pos] = x + c.store*c.feedback
+	c.pos = (c.pos + 1) % len(c.buf)
+	return out
+}
+
+// allpass is a Schroeder all-pass diffuser.
+type allpass struct {
+	buf []float64
+	pos int
+}
+
+func (a *allpass) process(x float64) float64 {
+	delayed := a.buf[a.pos]
+	a.buf[a.pos] = x + delayed*0.5
+	a.pos = (a.pos + 1) % len(a.buf)
+	return delayed - x
+}
+
+// Reverb is a stereo Freeverb: eight parallel damped combs followed by four
+// series all-passes per channel, fed with the mono sum of the input.
+type Reverb struct {
+	combsL, combsR         []comb
+	allpassesL, allpassesR []allpass
+}
+
+func NewReverb(sampleRate float64) *Reverb {
+	r := &Reverb{}
+	scale := func(n int) int {
+		return max(1, int(math.Round(float64(n)*sampleRate/reverbTuningRate)))
+	}
+	for _, n := range reverbCombTuning {
+		r.combsL = append(r.combsL, comb{buf: make([]float64, scale(n))})
+		r.combsR = append(r.combsR, comb{buf: make([]float64, scale(n+reverbStereoSpread))})
+	}
+	for _, n := range reverbAllpassTuning {
+		r.allpassesL = append(r.allpassesL, allpass{buf: make([]float64, scale(n))})
+		r.allpassesR = append(r.allpassesR, allpass{buf: make([]float64, scale(n+reverbStereoSpread))})
+	}
+	r.Set(0.5, 0.5)
+	return r
+}
+
+// Set sets the room size and damping, both 0-1. Bigger rooms ring longer;
+// more damping makes the tail darker.
+func (r *Reverb) Set(roomSize, damping float64) {
+	feedback := 0.7 + 0.28*math.Max(0, math.Min(1, roomSize))
+	damp := 0.4 * math.Max(0, math.Min(1, damping))
+	for i := range r.combsL {
+		r.combsL[i].feedback, r.combsL[i].damp = feedback, damp
+		r.combsR[i].feedback, r.combsR[i].damp = feedback, damp
+	}
+}
+
+// Reset clears the reverb tail.
+func (r *Reverb) Reset() {
+	for i := range r.combsL {
+		clear(r.combsL[i].buf)
+		clear(r.combsR[i].buf)
+		r.combsL[i].store, r.combsR[i].store = 0, 0
+	}
+	for i := range r.allpassesL {
+		clear(r.allpassesL[i].buf)
+		clear(r.allpassesR[i].buf)
+	}
+}
+
+// Process replaces left and right with the reverberated (fully wet) signal.
+func (r *Reverb) Process(left, right []float64) {
+	for i := range left {
+		in := (left[i] + right[i]) / 2
+		var outL, outR float64
+		for j := range r.combsL {
+			outL += r.combsL[j].process(in)
+			outR += r.combsR[j].process(in)
+		}
+		for j := range r.allpassesL {
+			outL = r.allpassesL[j].process(outL)
+			outR = r.allpassesR[j].process(outR)
+		}
+		left[i] = outL * reverbInputGain * reverbWetScale
+		right[i] = outR * reverbInputGain * reverbWetScale
+	}
+}
@@ -0,0 +1,37 @@
+package filter
+
+// tiltSlope is the shelf slope of a Tilt. A gentle slope spreads the tilt
+// over several octaves either side of the center, like a single straight
+// line through the spectrum.
+const tiltSlope = 0.5
+
+// Tilt pivots the spectrum around a center frequency with a matched pair of
+// shelves at that frequency: the low shelf cuts by as much as the high shelf
+// boosts (or the other way round), so the center stays at 0 dB.
+type Tilt struct {
+	low, high *Biquad
+}
+
+func NewTilt(center, sampleRate float64) *Tilt {
+	return &Tilt{
+		low:  New(LowShelf, center, tiltSlope, 0, sampleRate),
+		high: New(HighShelf, center, tiltSlope, 0, sampleRate),
+	}
+}
+
+// Set sets the center frequency in Hz and the tilt in dB: the difference
+// between the top and the bottom of the spectrum, positive for brighter.
+func (t *Tilt) Set(center, gainDB float64) {
+	t.low.Set(center, tiltSlope, -gainDB/2)
+	t.high.Set(center, tiltSlope, gainDB/2)
+}
+
+func (t *Tilt) Process(samples []float64) {
+	t.low.Process(samples)
+	t.high.Process(samples)
+}
+
+// Response returns the magnitude response |H(f)| at f Hz.
+func (t *Tilt) Response(f float64) float64 {
+	return t.low.Response(f) * t.high.Response(f)
+}
@@ -0,0 +1,205 @@
+package mixer
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Bounds is the range a parameter drifts within while evolving.
+type Bounds struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// clamp returns the bounds in order and limited to lo-hi.
+func (b Bounds) clamp(lo, hi float64) Bounds {
+	if b.Min > b.Max {
+		b.Min, b.Max = b.Max, b.Min
+	}
+	b.Min = math.Max(lo, math.Min(hi, b.Min))
+	b.Max = math.Max(lo, math.Min(hi, b.Max))
+	return b
+}
+
+// EvolveBounds are the bounds of each parameter the evolving mode drifts:
+// the main layer's color (0-100), bass and treble (-100 to 100), and the
+// output level in dB relative to the master volume (-24 to 6). A parameter
+// without bounds keeps its setting.
+type EvolveBounds struct {
+	Color  *Bounds `json:"color,omitempty"`
+	Bass   *Bounds `json:"bass,omitempty"`
+	Treble *Bounds `json:"treble,omitempty"`
+	Level  *Bounds `json:"level,omitempty"`
+}
+
+// clamp returns a copy of the bounds limited to each parameter's range.
+func (e EvolveBounds) clamp() EvolveBounds {
+	limit := func(b *Bounds, lo, hi float64) *Bounds {
+		if b == nil {
+			return nil
+		}
+		c := b.clamp(lo, hi)
+		return &c
+	}
+	return EvolveBounds{
+		Color:  limit(e.Color, 0, 100),
+		Bass:   limit(e.Bass, -100, 100),
+		Treble: limit(e.Treble, -100, 100),
+		Level:  limit(e.Level, -24, 6),
+	}
+}
+
+// Evolved are the values the evolving mode has drifted to, or the settings
+// of parameters it isn't drifting. Level is in dB.
+type Evolved struct {
+	Color  float64 `json:"color"`
+	Bass   float64 `json:"bass"`
+	Treble float64 `json:"treble"`
+	Level  float64 `json:"level"`
+}
+
+// walk is a constrained random walk. Its velocity wanders too (an
+// Ornstein-Uhlenbeck process), so the value drifts smoothly one way for a
+// while rather than jittering, and bounces off the ends of its range.
+type walk struct {
+	value    float64 // in the parameter's units
+	velocity float64 // fraction of the range per second
+	active   bool
+}
+
+// start begins the walk at value, or the nearest bound, if it isn't already
+// running.
+func (w *walk) start(value float64, b Bounds) {
+	if !w.active {
+		w.value, w.velocity, w.active = value, 0, true
+	}
+	w.value = math.Max(b.Min, math.Min(b.Max, w.value))
+}
+
+// step advances the walk by dt seconds. driftTime, in seconds, is about how
+// long the walk takes to wander across its range.
+func (w *walk) step(b Bounds, driftTime, dt float64, rng *rand.Rand) {
+	// The velocity keeps its direction for about an eighth of the drift
+	// time; its spread makes the walk diffuse across the range in about
+	// driftTime.
+	tau := driftTime / 8
+	spread := 1 / math.Sqrt(tau*driftTime)
+	w.velocity += -w.velocity*dt/tau + spread*math.Sqrt(2*dt/tau)*rng.NormFloat64()
+
+	w.value += w.velocity * (b.Max - b.Min) * dt
+	if w.value > b.Max {
+		w.value, w.velocity = 2*b.Max-w.value, -w.velocity
+	}
+	if w.value < b.Min {
+		w.value, w.velocity = 2*b.Min-w.value, -w.velocity
+	}
+	w.value = math.Max(b.Min, math.Min(b.Max, w.value))
+}
+
+// SetEvolve switches the evolving mode, which slowly drifts the main layer's
+// color, bass and treble and the output level within their bounds. Each
+// drift starts from the current setting.
+func (m *Mixer) SetEvolve(on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if on == m.evolve {
+		return
+	}
+	m.evolve = on
+	if !on {
+		m.walks = [4]walk{}
+	}
+	m.modulate(0)
+}
+
+func (m *Mixer) GetEvolve() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.evolve
+}
+
+// SetEvolveBounds sets the bounds of the evolving mode. Drifts already
+// running carry on from where they are, moved inside their new bounds.
+func (m *Mixer) SetEvolveBounds(bounds EvolveBounds) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.evolveBounds = bounds.clamp()
+	for i, b := range m.evolveBoundsList() {
+		if b == nil {
+			m.walks[i] = walk{}
+		}
+	}
+	m.modulate(0)
+}
+
+func (m *Mixer) GetEvolveBounds() EvolveBounds {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	// Already clamped; clamping again copies the bounds
+	return m.evolveBounds.clamp()
+}
+
+// SetEvolveDriftTime sets roughly how long, in minutes, a drifting
+// parameter takes to wander across its bounds (1 to 240).
+func (m *Mixer) SetEvolveDriftTime(minutes float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.evolveDriftTime = math.Max(1, math.Min(240, minutes))
+}
+
+func (m *Mixer) GetEvolveDriftTime() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.evolveDriftTime
+}
+
+// GetEvolved returns the current values of the drifting parameters.
+func (m *Mixer) GetEvolved() Evolved {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	values := m.evolveSettings()
+	for i := range values {
+		if m.walks[i].active {
+			values[i] = m.walks[i].value
+		}
+	}
+	return Evolved{Color: values[0], Bass: values[1], Treble: values[2], Level: values[3]}
+}
+
+// evolveBoundsList returns the bounds in walk order: color, bass, treble
+// and level.
+func (m *Mixer) evolveBoundsList() [4]*Bounds {
+	b := m.evolveBounds
+	return [4]*Bounds{b.Color, b.Bass, b.Treble, b.Level}
+}
+
+// evolveSettings returns the settings the drifts start from, in walk order.
+func (m *Mixer) evolveSettings() [4]float64 {
+	main := m.layers[0]
+	return [4]float64{main.colorSlider, main.bassGain, main.trebleGain, 0}
+}
+
+// drift advances the evolving mode by dt seconds. It returns the offsets
+// from the main layer's color, bass and treble sliders and sets the level
+// offset.
+func (m *Mixer) drift(dt float64) (color, bass, treble float64) {
+	if !m.evolve {
+		m.levelMod = 0
+		return 0, 0, 0
+	}
+	settings := m.evolveSettings()
+	var offsets [4]float64
+	for i, b := range m.evolveBoundsList() {
+		if b == nil {
+			continue
+		}
+		w := &m.walks[i]
+		w.start(settings[i], *b)
+		if dt > 0 {
+			w.step(*b, m.evolveDriftTime*60, dt, m.evolveRNG)
+		}
+		offsets[i] = w.value - settings[i]
+	}
+	m.levelMod = offsets[3]
+	return offsets[0], offsets[1], offsets[2]
+}
@@ -0,0 +1,242 @@
+package mixer
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/agusx1211/pink-noise/internal/filter"
+	"github.com/agusx1211/pink-noise/internal/noise"
+)
+
+// layer is one noise source with its own color, EQ and gain. The mixer sums
+// all layers before applying the master volume.
+type layer struct {
+	// noiseGen and sideGen get identical settings and share event seeds, so
+	// they play the same sound with independent noise; mixing them gives
+	// left and right channels of any width. Ocean waves and shushes are
+	// shared events and stay in step. Raindrops are drawn with the noise, so
+	// each stream has its own: identical drops would cancel out of one
+	// channel in the mid/side mix, while independent ones fall on both
+	// sides at wide settings and become mono ones at width 0.
+	noiseGen  *noise.Generator
+	sideGen   *noise.Generator
+	sound     noise.Sound
+	colorMode noise.ColorMode
+	width     float64 // stereo width currently applied, 0-1
+
+	colorSlider float64
+	bassGain    float64
+	trebleGain  float64
+	gain        float64
+	currentGain float64
+	mute        bool
+
+	rainDensity   float64
+	rainIntensity float64
+
+	oceanPeriod     float64
+	oceanDepth      float64
+	oceanRandomness float64
+
+	shushRate       float64
+	shushDuty       float64
+	shushRandomness float64
+
+	greyPhon float64
+
+	// Offsets from the modulation matrix, in slider units
+	colorMod  float64
+	bassMod   float64
+	trebleMod float64
+
+	lowShelfL  *filter.Biquad
+	lowShelfR  *filter.Biquad
+	highShelfL *filter.Biquad
+	highShelfR *filter.Biquad
+}
+
+func newLayer(sampleRate int) *layer {
+	seed, eventSeed := rand.Int63(), rand.Int63()
+	l := &layer{
+		noiseGen:    noise.NewSeededGenerator(sampleRate, seed, eventSeed),
+		sideGen:     noise.NewSeededGenerator(sampleRate, seed+1, eventSeed),
+		sound:       noise.SoundNoise,
+		colorMode:   noise.ColorBlend,
+		colorSlider: 25, // pink noise default
+		gain:        1,
+		currentGain: 1,
+		lowShelfL:   filter.NewShelf(filter.LowShelf, 300, 0, float64(sampleRate)),
+		lowShelfR:   filter.NewShelf(filter.LowShelf, 300, 0, float64(sampleRate)),
+		highShelfL:  filter.NewShelf(filter.HighShelf, 3000, 0, float64(sampleRate)),
+		highShelfR:  filter.NewShelf(filter.HighShelf, 3000, 0, float64(sampleRate)),
+	}
+	l.setRain(50, 50)
+	l.setOcean(8, 70, 30)
+	l.setShush(50, 60, 20)
+	l.setGrey(40)
+	return l
+}
+
+func (l *layer) setRain(density, intensity float64) {
+	l.rainDensity = math.Max(0, math.Min(100, density))
+	l.rainIntensity = math.Max(0, math.Min(100, intensity))
+	l.noiseGen.SetRain(l.rainDensity, l.rainIntensity)
+	l.sideGen.SetRain(l.rainDensity, l.rainIntensity)
+}
+
+func (l *layer) setOcean(period, depth, randomness float64) {
+	l.oceanPeriod = math.Max(2, math.Min(30, period))
+	l.oceanDepth = math.Max(0, math.Min(100, depth))
+	l.oceanRandomness = math.Max(0, math.Min(100, randomness))
+	l.noiseGen.SetOcean(l.oceanPeriod, l.oceanDepth, l.oceanRandomness)
+	l.sideGen.SetOcean(l.oceanPeriod, l.oceanDepth, l.oceanRandomness)
+}
+
+func (l *layer) setShush(rate, duty, randomness float64) {
+	l.shushRate = math.Max(10, math.Min(120, rate))
+	l.shushDuty = math.Max(10, math.Min(90, duty))
+	l.shushRandomness = math.Max(0, math.Min(100, randomness))
+	l.noiseGen.SetShush(l.shushRate, l.shushDuty, l.shushRandomness)
+	l.sideGen.SetShush(l.shushRate, l.shushDuty, l.shushRandomness)
+}
+
+func (l *layer) setGrey(phon float64) {
+	l.greyPhon = math.Max(20, math.Min(80, phon))
+	l.noiseGen.SetGrey(l.greyPhon)
+	l.sideGen.SetGrey(l.greyPhon)
+}
+
+func (l *layer) setColorMode(mode noise.ColorMode) {
+	l.colorMode = mode
+	l.noiseGen.SetColorMode(mode)
+	l.sideGen.SetColorMode(mode)
+}
+
+func (l *layer) setSpectrum(points []noise.SpectrumPoint) {
+	l.noiseGen.SetSpectrum(points)
+	l.sideGen.SetSpectrum(points)
+}
+
+// setShelves moves the bass and treble shelf corners and sets their slope,
+// keeping the current gains.
+func (l *layer) setShelves(bassFrequency, trebleFrequency, slope float64) {
+	bassDB, trebleDB := l.shelfGains()
+	l.lowShelfL.Set(bassFrequency, slope, bassDB)
+	l.lowShelfR.Set(bassFrequency, slope, bassDB)
+	l.highShelfL.Set(trebleFrequency, slope, trebleDB)
+	l.highShelfR.Set(trebleFrequency, slope, trebleDB)
+}
+
+// power returns the layer's average power response at f, after its EQ and
+// gain. Muted layers are silent.
+func (l *layer) power(f float64) float64 {
+	if l.mute {
+		return 0
+	}
+	shelves := l.lowShelfL.Response(f) * l.highShelfL.Response(f)
+	return l.noiseGen.SoundPower(l.sound, l.color(), f) * shelves * shelves * l.gain * l.gain
+}
+
+// reseed reseeds both generators, keeping their events in step.
+func (l *layer) reseed(seed int64) {
+	r := rand.New(rand.NewSource(seed))
+	eventSeed := r.Int63()
+	l.noiseGen.Reseed(r.Int63(), eventSeed)
+	l.sideGen.Reseed(r.Int63(), eventSeed)
+}
+
+func (l *layer) setBass(value float64) {
+	l.bassGain = math.Max(-100, math.Min(100, value))
+	l.updateShelfGains()
+}
+
+func (l *layer) setTreble(value float64) {
+	l.trebleGain = math.Max(-100, math.Min(100, value))
+	l.updateShelfGains()
+}
+
+// modulate sets the modulation offsets of the color, bass and treble
+// sliders.
+func (l *layer) modulate(color, bass, treble float64) {
+	l.colorMod = color
+	if bass != l.bassMod || treble != l.trebleMod {
+		l.bassMod, l.trebleMod = bass, treble
+		l.updateShelfGains()
+	}
+}
+
+// color returns the color slider with modulation applied.
+func (l *layer) color() float64 {
+	return math.Max(0, math.Min(100, l.colorSlider+l.colorMod))
+}
+
+// shelfGains returns the bass and treble shelf gains in dB with modulation
+// applied.
+func (l *layer) shelfGains() (float64, float64) {
+	bass := math.Max(-100, math.Min(100, l.bassGain+l.bassMod))
+	treble := math.Max(-100, math.Min(100, l.trebleGain+l.trebleMod))
+	return sliderToGainDB(bass), sliderToGainDB(treble)
+}
+
+func (l *layer) updateShelfGains() {
+	bassDB, trebleDB := l.shelfGains()
+	l.lowShelfL.UpdateGain(bassDB)
+	l.lowShelfR.UpdateGain(bassDB)
+	l.highShelfL.UpdateGain(trebleDB)
+	l.highShelfR.UpdateGain(trebleDB)
+}
+
+func (l *layer) targetGain() float64 {
+	if l.mute {
+		return 0
+	}
+	return l.gain
+}
+
+// mixInto generates samples for this layer and adds them to left and right.
+// width (0-1) sets how decorrelated the two channels are.
+func (l *layer) mixInto(left, right []float64, width float64) {
+	target := l.targetGain()
+	if target == 0 && l.currentGain < 1e-4 {
+		// Fully muted: skip generation but keep the gain settled
+		l.currentGain = 0
+		l.width = width
+		return
+	}
+
+	samples := len(left)
+
+	// Generate the layer's sound as two matched, uncorrelated streams
+	color := l.color()
+	mid := l.noiseGen.GenerateSound(l.sound, color, samples, 1.0)
+	side := l.sideGen.GenerateSound(l.sound, color, samples, 1.0)
+
+	// L = cos θ·mid + sin θ·side, R = cos θ·mid - sin θ·side keeps each
+	// channel's level constant while the L/R correlation falls from 1 at
+	// θ = 0 to 0 at θ = π/4. θ ramps across the buffer so width changes
+	// don't click.
+	layerL := make([]float64, samples)
+	layerR := make([]float64, samples)
+	from, to := l.width*math.Pi/4, width*math.Pi/4
+	for i := range samples {
+		theta := from + (to-from)*float64(i)/float64(samples)
+		m := mid[i] * math.Cos(theta)
+		s := side[i] * math.Sin(theta)
+		layerL[i] = m + s
+		layerR[i] = m - s
+	}
+	l.width = width
+
+	// Apply EQ filters
+	l.lowShelfL.Process(layerL)
+	l.highShelfL.Process(layerL)
+	l.lowShelfR.Process(layerR)
+	l.highShelfR.Process(layerR)
+
+	// Apply layer gain with smoothing so mute and gain changes don't click
+	for i := range samples {
+		l.currentGain += (target - l.currentGain) * 0.001
+		left[i] += layerL[i] * l.currentGain
+		right[i] += layerR[i] * l.currentGain
+	}
+}
@@ -2,7 +2,10 @@ package mixer
 
 import (
 	"math"
+	"math/rand"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/agusx1211/pink-noise/internal/filter"
 	"github.com/agusx1211/pink-noise/internal/noise"
@@ -11,34 +14,159 @@ import (
 type Mixer struct {
 	mu sync.RWMutex
 
-	noiseGen   *noise.Generator
-	sampleRate int
+	layers      []*layer
+	sampleRate  int
+	stereoWidth float64 // 0-100
+
+	// Bass and treble shelf corners and slope, shared by every layer
+	bassFrequency   float64 // Hz
+	trebleFrequency float64 // Hz
+	shelfSlope      float64
+
+	heartbeat      *noise.Heartbeat
+	heartbeatBPM   float64
+	heartbeatLevel float64 // 0-100
+
+	tone        *noise.Tone
+	toneMode    noise.ToneMode
+	toneCarrier float64 // Hz
+	toneBeat    float64 // Hz
+	toneLevel   float64 // 0-100
 
 	power        bool
 	masterVolume float64
 	targetVolume float64
-	colorSlider  float64
-	bassGain     float64
-	trebleGain   float64
 
-	lowShelfL  *filter.Biquad
-	lowShelfR  *filter.Biquad
-	highShelfL *filter.Biquad
-	highShelfR *filter.Biquad
+	timerDeadline time.Time
+	timerFade     time.Duration
+	timerLevel    float64 // sleep timer fade gain reached so far
+
+	// Power envelope: ramps between 0 and 1 when power toggles
+	envelope float64
+	fadeIn   float64 // seconds
+	fadeOut  float64 // seconds
+	draining bool
+
+	limiter             *filter.Limiter
+	limiterThreshold    float64 // dBFS
+	limiterRelease      float64 // ms
+	compressor          bool
+	compressorThreshold float64 // dBFS
+
+	// Tinnitus relief: a band-stop notch around the tinnitus pitch,
+	// crossfaded in and out so toggling it doesn't click
+	tinnitus          bool
+	tinnitusFrequency float64 // Hz
+	tinnitusWidth     float64 // octaves
+	tinnitusMix       float64
+	notchL            *filter.Notch
+	notchR            *filter.Notch
+
+	// LFO modulation matrix, see modulation.go. volumeMod and widthMod are
+	// the current offsets; color, bass and treble offsets live on each layer.
+	modulations []Modulation
+	lfos        []lfo
+	modRNG      *rand.Rand
+	volumeMod   float64
+	widthMod    float64
+
+	// Evolving mode, see evolve.go: constrained random walks of the main
+	// layer's color, bass and treble and the level (levelMod, in dB)
+	evolve          bool
+	evolveBounds    EvolveBounds
+	evolveDriftTime float64 // minutes
+	evolveRNG       *rand.Rand
+	walks           [4]walk
+	levelMod        float64
+
+	// Tilt EQ over the whole mix, pivoting around tiltCenter
+	tilt       float64 // -100 (darker) to 100 (brighter)
+	tiltCenter float64 // Hz
+	tiltL      *filter.Tilt
+	tiltR      *filter.Tilt
+
+	// Parametric EQ applied to the whole mix, one biquad per band and channel
+	eq  []filter.Band
+	eqL []*filter.Biquad
+	eqR []*filter.Biquad
+
+	// Reverb, mixed in by reverbMix; reverbWet follows it smoothly
+	reverb         *filter.Reverb
+	reverbMix      float64 // 0-100
+	reverbRoomSize float64 // 0-100
+	reverbDamping  float64 // 0-100
+	reverbWet      float64
+
+	// Room correction: convolution with a measured impulse response,
+	// crossfaded in and out like the tinnitus notch
+	convolution     bool
+	convolutionMix  float64
+	impulseResponse string // path of the loaded response
+	convolverL      *filter.Convolver
+	convolverR      *filter.Convolver
+
+	// A newly loaded (or unloaded) response waits here until the old one
+	// has faded out to the dry signal
+	swapConvolvers bool
+	nextConvolverL *filter.Convolver
+	nextConvolverR *filter.Convolver
 }
 
-func NewMixer(sampleRate int) *Mixer {
+// NewMixer creates a mixer with the given number of layers. Layer 0 is the
+// main layer and starts unmuted; the others start muted.
+func NewMixer(sampleRate, layerCount int) *Mixer {
+	layers := make([]*layer, max(1, layerCount))
+	for i := range layers {
+		layers[i] = newLayer(sampleRate)
+		if i > 0 {
+			layers[i].mute = true
+			layers[i].currentGain = 0
+		}
+	}
+
 	return &Mixer{
-		noiseGen:     noise.NewGenerator(sampleRate),
+		layers:       layers,
 		sampleRate:   sampleRate,
+		heartbeat:    noise.NewHeartbeat(sampleRate),
+		heartbeatBPM: 70,
+		tone:         noise.NewTone(sampleRate),
+		toneMode:     noise.ToneBinaural,
+		toneCarrier:  200,
+		toneBeat:     6,
 		power:        false,
 		masterVolume: 0.5,
 		targetVolume: 0.5,
-		colorSlider:  25, // pink noise default
-		lowShelfL:    filter.NewShelf(filter.LowShelf, 300, 0, float64(sampleRate)),
-		lowShelfR:    filter.NewShelf(filter.LowShelf, 300, 0, float64(sampleRate)),
-		highShelfL:   filter.NewShelf(filter.HighShelf, 3000, 0, float64(sampleRate)),
-		highShelfR:   filter.NewShelf(filter.HighShelf, 3000, 0, float64(sampleRate)),
+		timerFade:    time.Minute,
+		timerLevel:   1,
+		fadeIn:       2,
+		fadeOut:      3,
+
+		bassFrequency:   300,
+		trebleFrequency: 3000,
+		shelfSlope:      1,
+
+		limiter:             filter.NewLimiter(float64(sampleRate)),
+		limiterThreshold:    -1,
+		limiterRelease:      100,
+		compressorThreshold: -18,
+
+		modRNG: rand.New(rand.NewSource(rand.Int63())),
+
+		evolveDriftTime: 20,
+		evolveRNG:       rand.New(rand.NewSource(rand.Int63())),
+
+		tiltCenter: 1000,
+		tiltL:      filter.NewTilt(1000, float64(sampleRate)),
+		tiltR:      filter.NewTilt(1000, float64(sampleRate)),
+
+		reverb:         filter.NewReverb(float64(sampleRate)),
+		reverbRoomSize: 50,
+		reverbDamping:  50,
+
+		tinnitusFrequency: 4000,
+		tinnitusWidth:     1,
+		notchL:            filter.NewNotch(4000, 1, float64(sampleRate)),
+		notchR:            filter.NewNotch(4000, 1, float64(sampleRate)),
 	}
 }
 
@@ -46,14 +174,78 @@ func (m *Mixer) SetPower(on bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.power = on
+	if !on {
+		m.timerDeadline = time.Time{}
+	}
 }
 
+// GetPower reports whether the mixer is producing sound. After power is
+// switched off it stays true until the fade-out reaches silence.
 func (m *Mixer) GetPower() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.power || m.envelope > 0
+}
+
+// GetTargetPower returns the requested power state, ignoring any fade in progress.
+func (m *Mixer) GetTargetPower() bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	return m.power
 }
 
+func (m *Mixer) SetFadeIn(seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fadeIn = math.Max(0, math.Min(60, seconds))
+}
+
+func (m *Mixer) GetFadeIn() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.fadeIn
+}
+
+func (m *Mixer) SetFadeOut(seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fadeOut = math.Max(0, math.Min(60, seconds))
+}
+
+func (m *Mixer) GetFadeOut() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.fadeOut
+}
+
+// Drain fades the output to silence without touching the power state, so
+// the persisted state still reflects the user's choice. It blocks until the
+// fade-out completes or the timeout expires.
+func (m *Mixer) Drain(timeout time.Duration) {
+	m.mu.Lock()
+	m.draining = true
+	m.mu.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		m.mu.RLock()
+		silent := m.envelope == 0
+		m.mu.RUnlock()
+		if silent {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// envelopeStep returns the per-sample envelope increment for a fade of the given length.
+func (m *Mixer) envelopeStep(seconds float64) float64 {
+	if seconds <= 0 {
+		return 1
+	}
+	return 1 / (seconds * float64(m.sampleRate))
+}
+
 func (m *Mixer) SetMasterVolume(volume float64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -66,16 +258,13 @@ func (m *Mixer) GetMasterVolume() float64 {
 	return m.targetVolume
 }
 
+// SetColor, SetBass and SetTreble control the main layer (layer 0).
 func (m *Mixer) SetColor(value float64) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.colorSlider = math.Max(0, math.Min(100, value))
+	m.SetLayerColor(0, value)
 }
 
 func (m *Mixer) GetColor() float64 {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return m.colorSlider
+	return m.GetLayerColor(0)
 }
 
 // sliderToGainDB maps -100..+100 slider to -12..+12 dB
@@ -84,77 +273,1010 @@ func sliderToGainDB(slider float64) float64 {
 }
 
 func (m *Mixer) SetBass(value float64) {
+	m.SetLayerBass(0, value)
+}
+
+func (m *Mixer) GetBass() float64 {
+	return m.GetLayerBass(0)
+}
+
+func (m *Mixer) SetTreble(value float64) {
+	m.SetLayerTreble(0, value)
+}
+
+func (m *Mixer) GetTreble() float64 {
+	return m.GetLayerTreble(0)
+}
+
+// SetBassFrequency sets the corner of the bass shelf in Hz (40 to 1000).
+// Small speakers that can't reproduce deep bass need a higher corner.
+func (m *Mixer) SetBassFrequency(hz float64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.bassGain = math.Max(-100, math.Min(100, value))
-	gainDB := sliderToGainDB(m.bassGain)
-	m.lowShelfL.UpdateGain(gainDB)
-	m.lowShelfR.UpdateGain(gainDB)
+	m.bassFrequency = math.Max(40, math.Min(1000, hz))
+	m.updateShelves()
 }
 
-func (m *Mixer) GetBass() float64 {
+func (m *Mixer) GetBassFrequency() float64 {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.bassGain
+	return m.bassFrequency
 }
 
-func (m *Mixer) SetTreble(value float64) {
+// SetTrebleFrequency sets the corner of the treble shelf in Hz (1000 to
+// 16000, and below Nyquist).
+func (m *Mixer) SetTrebleFrequency(hz float64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.trebleGain = math.Max(-100, math.Min(100, value))
-	gainDB := sliderToGainDB(m.trebleGain)
-	m.highShelfL.UpdateGain(gainDB)
-	m.highShelfR.UpdateGain(gainDB)
+	m.trebleFrequency = math.Max(1000, math.Min(math.Min(16000, float64(m.sampleRate)*0.45), hz))
+	m.updateShelves()
 }
 
-func (m *Mixer) GetTreble() float64 {
+func (m *Mixer) GetTrebleFrequency() float64 {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.trebleGain
+	return m.trebleFrequency
 }
 
-func (m *Mixer) ReseedRNG(seed int64) {
+// SetShelfSlope sets the slope S of both shelves (0.1 to 1). Lower values
+// spread the transition over more octaves; 1 is the steepest slope without
+// overshoot.
+func (m *Mixer) SetShelfSlope(slope float64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.noiseGen.Reseed(seed)
+	m.shelfSlope = math.Max(0.1, math.Min(1, slope))
+	m.updateShelves()
 }
 
-func (m *Mixer) Mix(samples int) []float64 {
+func (m *Mixer) GetShelfSlope() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.shelfSlope
+}
+
+func (m *Mixer) updateShelves() {
+	for _, l := range m.layers {
+		l.setShelves(m.bassFrequency, m.trebleFrequency, m.shelfSlope)
+	}
+}
+
+// SetStereoWidth sets how decorrelated the left and right channels are,
+// from 0 (mono) to 100 (fully independent noise in each channel).
+func (m *Mixer) SetStereoWidth(width float64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.stereoWidth = math.Max(0, math.Min(100, width))
+}
 
-	result := make([]float64, samples*2)
+func (m *Mixer) GetStereoWidth() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.stereoWidth
+}
 
-	if !m.power {
-		// Smooth volume to zero when off
-		for i := range samples {
-			m.masterVolume += (0 - m.masterVolume) * 0.001
-			_ = i
-		}
-		return result
+func (m *Mixer) LayerCount() int {
+	return len(m.layers)
+}
+
+// layer returns the layer at index n, or nil if out of range. Caller must hold m.mu.
+func (m *Mixer) layer(n int) *layer {
+	if n < 0 || n >= len(m.layers) {
+		return nil
+	}
+	return m.layers[n]
+}
+
+func (m *Mixer) SetLayerColor(n int, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.colorSlider = math.Max(0, math.Min(100, value))
 	}
+}
 
-	// Generate blended noise (mono)
-	mono := m.noiseGen.GenerateBlended(m.colorSlider, samples, 1.0)
+func (m *Mixer) GetLayerColor(n int) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.colorSlider
+	}
+	return 0
+}
 
-	// Split to L/R for independent filter state
-	left := make([]float64, samples)
-	right := make([]float64, samples)
-	copy(left, mono)
-	copy(right, mono)
+func (m *Mixer) SetLayerBass(n int, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.setBass(value)
+	}
+}
 
-	// Apply EQ filters
-	m.lowShelfL.Process(left)
-	m.highShelfL.Process(left)
-	m.lowShelfR.Process(right)
-	m.highShelfR.Process(right)
+func (m *Mixer) GetLayerBass(n int) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.bassGain
+	}
+	return 0
+}
 
-	// Apply volume with smoothing
-	for i := range samples {
-		m.masterVolume += (m.targetVolume - m.masterVolume) * 0.001
-		result[i*2] = math.Max(-1, math.Min(1, left[i]*m.masterVolume))
-		result[i*2+1] = math.Max(-1, math.Min(1, right[i]*m.masterVolume))
+func (m *Mixer) SetLayerTreble(n int, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.setTreble(value)
 	}
+}
 
-	return result
+func (m *Mixer) GetLayerTreble(n int) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.trebleGain
+	}
+	return 0
+}
+
+func (m *Mixer) SetLayerGain(n int, gain float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.gain = math.Max(0, math.Min(1, gain))
+	}
+}
+
+func (m *Mixer) GetLayerGain(n int) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.gain
+	}
+	return 0
+}
+
+func (m *Mixer) SetLayerMute(n int, mute bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.mute = mute
+	}
+}
+
+func (m *Mixer) GetLayerMute(n int) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.mute
+	}
+	return false
+}
+
+func (m *Mixer) SetLayerSound(n int, sound noise.Sound) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.sound = sound
+	}
+}
+
+func (m *Mixer) GetLayerSound(n int) noise.Sound {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.sound
+	}
+	return noise.SoundNoise
+}
+
+func (m *Mixer) SetLayerColorMode(n int, mode noise.ColorMode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.setColorMode(mode)
+	}
+}
+
+func (m *Mixer) GetLayerColorMode(n int) noise.ColorMode {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.colorMode
+	}
+	return noise.ColorBlend
+}
+
+// SetLayerSpectrum sets the curve layer n plays when its sound is spectrum.
+func (m *Mixer) SetLayerSpectrum(n int, points []noise.SpectrumPoint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.setSpectrum(points)
+	}
+}
+
+func (m *Mixer) GetLayerSpectrum(n int) []noise.SpectrumPoint {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.noiseGen.Spectrum()
+	}
+	return nil
+}
+
+func (m *Mixer) SetLayerRainDensity(n int, density float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.setRain(density, l.rainIntensity)
+	}
+}
+
+func (m *Mixer) GetLayerRainDensity(n int) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.rainDensity
+	}
+	return 0
+}
+
+func (m *Mixer) SetLayerRainIntensity(n int, intensity float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.setRain(l.rainDensity, intensity)
+	}
+}
+
+func (m *Mixer) GetLayerRainIntensity(n int) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.rainIntensity
+	}
+	return 0
+}
+
+func (m *Mixer) SetLayerOceanPeriod(n int, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.setOcean(seconds, l.oceanDepth, l.oceanRandomness)
+	}
+}
+
+func (m *Mixer) GetLayerOceanPeriod(n int) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.oceanPeriod
+	}
+	return 0
+}
+
+func (m *Mixer) SetLayerOceanDepth(n int, depth float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.setOcean(l.oceanPeriod, depth, l.oceanRandomness)
+	}
+}
+
+func (m *Mixer) GetLayerOceanDepth(n int) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.oceanDepth
+	}
+	return 0
+}
+
+func (m *Mixer) SetLayerOceanRandomness(n int, randomness float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.setOcean(l.oceanPeriod, l.oceanDepth, randomness)
+	}
+}
+
+func (m *Mixer) GetLayerOceanRandomness(n int) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.oceanRandomness
+	}
+	return 0
+}
+
+func (m *Mixer) SetLayerShushRate(n int, rate float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.setShush(rate, l.shushDuty, l.shushRandomness)
+	}
+}
+
+func (m *Mixer) GetLayerShushRate(n int) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.shushRate
+	}
+	return 0
+}
+
+func (m *Mixer) SetLayerShushDuty(n int, duty float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.setShush(l.shushRate, duty, l.shushRandomness)
+	}
+}
+
+func (m *Mixer) GetLayerShushDuty(n int) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.shushDuty
+	}
+	return 0
+}
+
+func (m *Mixer) SetLayerShushRandomness(n int, randomness float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.setShush(l.shushRate, l.shushDuty, randomness)
+	}
+}
+
+func (m *Mixer) GetLayerShushRandomness(n int) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.shushRandomness
+	}
+	return 0
+}
+
+// SetLayerGreyPhon sets the equal-loudness level, in phon, that layer n's
+// grey noise is flat at.
+func (m *Mixer) SetLayerGreyPhon(n int, phon float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.layer(n); l != nil {
+		l.setGrey(phon)
+	}
+}
+
+func (m *Mixer) GetLayerGreyPhon(n int) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if l := m.layer(n); l != nil {
+		return l.greyPhon
+	}
+	return 0
+}
+
+func (m *Mixer) SetHeartbeatBPM(bpm float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.heartbeatBPM = math.Max(40, math.Min(140, bpm))
+	m.heartbeat.SetBPM(m.heartbeatBPM)
+}
+
+func (m *Mixer) GetHeartbeatBPM() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.heartbeatBPM
+}
+
+// SetHeartbeatLevel sets the heartbeat level (0-100); 0 disables it.
+func (m *Mixer) SetHeartbeatLevel(level float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.heartbeatLevel = math.Max(0, math.Min(100, level))
+}
+
+func (m *Mixer) GetHeartbeatLevel() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.heartbeatLevel
+}
+
+func (m *Mixer) SetToneMode(mode noise.ToneMode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.toneMode = mode
+	m.tone.SetMode(mode)
+}
+
+func (m *Mixer) GetToneMode() noise.ToneMode {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.toneMode
+}
+
+// SetToneCarrier sets the tone carrier frequency in Hz (50-1000).
+func (m *Mixer) SetToneCarrier(hz float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.toneCarrier = math.Max(50, math.Min(1000, hz))
+	m.tone.SetCarrier(m.toneCarrier)
+}
+
+func (m *Mixer) GetToneCarrier() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.toneCarrier
+}
+
+// SetToneBeat sets the beat frequency in Hz (0.5-40).
+func (m *Mixer) SetToneBeat(hz float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.toneBeat = math.Max(0.5, math.Min(40, hz))
+	m.tone.SetBeat(m.toneBeat)
+}
+
+func (m *Mixer) GetToneBeat() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.toneBeat
+}
+
+// SetToneLevel sets the tone level (0-100); 0 disables it.
+func (m *Mixer) SetToneLevel(level float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.toneLevel = math.Max(0, math.Min(100, level))
+}
+
+func (m *Mixer) GetToneLevel() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.toneLevel
+}
+
+// SetLimiterThreshold sets the output ceiling in dBFS (-24 to 0).
+func (m *Mixer) SetLimiterThreshold(dB float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limiterThreshold = math.Max(-24, math.Min(0, dB))
+	m.limiter.SetThreshold(m.limiterThreshold)
+}
+
+func (m *Mixer) GetLimiterThreshold() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.limiterThreshold
+}
+
+// SetLimiterRelease sets the limiter release time in milliseconds (10 to 2000).
+func (m *Mixer) SetLimiterRelease(ms float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limiterRelease = math.Max(10, math.Min(2000, ms))
+	m.limiter.SetRelease(m.limiterRelease)
+}
+
+func (m *Mixer) GetLimiterRelease() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.limiterRelease
+}
+
+func (m *Mixer) SetCompressor(on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.compressor = on
+	m.limiter.SetCompressor(m.compressor, m.compressorThreshold)
+}
+
+func (m *Mixer) GetCompressor() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.compressor
+}
+
+// SetCompressorThreshold sets the compressor threshold in dBFS (-40 to 0).
+func (m *Mixer) SetCompressorThreshold(dB float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.compressorThreshold = math.Max(-40, math.Min(0, dB))
+	m.limiter.SetCompressor(m.compressor, m.compressorThreshold)
+}
+
+// GetGainReduction returns the gain reduction applied by the limiter and
+// compressor in the last buffer, in dB.
+func (m *Mixer) GetGainReduction() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.limiter.GainReduction()
+}
+
+// SetTinnitus enables or disables the tinnitus relief notch.
+func (m *Mixer) SetTinnitus(on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tinnitus = on
+}
+
+func (m *Mixer) GetTinnitus() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tinnitus
+}
+
+// SetTinnitusFrequency sets the center of the notch in Hz (250 to 12000),
+// matched to the pitch of the listener's tinnitus.
+func (m *Mixer) SetTinnitusFrequency(hz float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tinnitusFrequency = math.Max(250, math.Min(12000, hz))
+	m.notchL.Set(m.tinnitusFrequency, m.tinnitusWidth)
+	m.notchR.Set(m.tinnitusFrequency, m.tinnitusWidth)
+}
+
+func (m *Mixer) GetTinnitusFrequency() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tinnitusFrequency
+}
+
+// SetTinnitusWidth sets the width of the notch in octaves (0.25 to 2).
+func (m *Mixer) SetTinnitusWidth(octaves float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tinnitusWidth = math.Max(0.25, math.Min(2, octaves))
+	m.notchL.Set(m.tinnitusFrequency, m.tinnitusWidth)
+	m.notchR.Set(m.tinnitusFrequency, m.tinnitusWidth)
+}
+
+func (m *Mixer) GetTinnitusWidth() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tinnitusWidth
+}
+
+// SetTilt tilts the whole spectrum around the tilt center, from -100
+// (darker: bass up, treble down by 6 dB each) to 100 (brighter).
+func (m *Mixer) SetTilt(value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tilt = math.Max(-100, math.Min(100, value))
+	m.updateTilt()
+}
+
+func (m *Mixer) GetTilt() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tilt
+}
+
+// SetTiltCenter sets the frequency the tilt pivots around in Hz (200 to
+// 5000).
+func (m *Mixer) SetTiltCenter(hz float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tiltCenter = math.Max(200, math.Min(5000, hz))
+	m.updateTilt()
+}
+
+func (m *Mixer) GetTiltCenter() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tiltCenter
+}
+
+func (m *Mixer) updateTilt() {
+	gainDB := sliderToGainDB(m.tilt)
+	m.tiltL.Set(m.tiltCenter, gainDB)
+	m.tiltR.Set(m.tiltCenter, gainDB)
+}
+
+// SetEQ replaces the parametric EQ bands, applied in order. Bands keep
+// their filter state when only their frequency, Q or gain changes. Bands
+// without a valid type are dropped.
+func (m *Mixer) SetEQ(bands []filter.Band) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sr := float64(m.sampleRate)
+	bands = slices.DeleteFunc(slices.Clone(bands), func(b filter.Band) bool {
+		return b.Validate() != nil
+	})
+	eq := make([]filter.Band, len(bands))
+	eqL := make([]*filter.Biquad, len(bands))
+	eqR := make([]*filter.Biquad, len(bands))
+	for i, b := range bands {
+		b = b.Clamp(sr)
+		eq[i] = b
+		if i < len(m.eq) && m.eq[i].Type == b.Type {
+			eqL[i], eqR[i] = m.eqL[i], m.eqR[i]
+			eqL[i].Set(b.Freq, b.Q, b.Gain)
+			eqR[i].Set(b.Freq, b.Q, b.Gain)
+		} else {
+			eqL[i] = filter.NewBand(b, sr)
+			eqR[i] = filter.NewBand(b, sr)
+		}
+	}
+	m.eq, m.eqL, m.eqR = eq, eqL, eqR
+}
+
+func (m *Mixer) GetEQ() []filter.Band {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]filter.Band{}, m.eq...)
+}
+
+// Response returns the level in dB at each of the given frequencies of what
+// the listener hears before the master volume: every unmuted layer's sound,
+// EQ and gain, followed by the tilt, parametric EQ, room correction (left
+// channel) and the tinnitus notch. Levels are relative to full-scale white
+// noise and floored at -120 dB. Without frequencies, a sixth-octave grid
+// from 20 Hz to 20 kHz is used.
+func (m *Mixer) Response(freqs []float64) []noise.SpectrumPoint {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(freqs) == 0 {
+		top := math.Min(20000, float64(m.sampleRate)*0.49)
+		for f := 20.0; f <= top; f *= math.Pow(2, 1.0/6) {
+			freqs = append(freqs, f)
+		}
+	}
+
+	points := make([]noise.SpectrumPoint, 0, len(freqs))
+	for _, f := range freqs {
+		if f <= 0 || f >= float64(m.sampleRate)/2 {
+			continue
+		}
+		var power float64
+		for _, l := range m.layers {
+			power += l.power(f)
+		}
+		eq := m.tiltL.Response(f) * filter.CascadeResponse(m.eqL, f)
+		if m.convolution && m.convolverL != nil {
+			eq *= m.convolverL.Response(f)
+		}
+		if m.tinnitus {
+			eq *= m.notchL.Response(f)
+		}
+		power *= eq * eq
+		points = append(points, noise.SpectrumPoint{Freq: f, DB: 10 * math.Log10(math.Max(power, 1e-12))})
+	}
+	return points
+}
+
+// SetReverbMix sets the reverb wet/dry balance (0 = dry, which bypasses
+// the reverb, to 100 = fully wet).
+func (m *Mixer) SetReverbMix(mix float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reverbMix = math.Max(0, math.Min(100, mix))
+}
+
+func (m *Mixer) GetReverbMix() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.reverbMix
+}
+
+// SetReverbRoomSize sets the reverb room size (0-100); bigger rooms ring
+// longer.
+func (m *Mixer) SetReverbRoomSize(size float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reverbRoomSize = math.Max(0, math.Min(100, size))
+	m.reverb.Set(m.reverbRoomSize/100, m.reverbDamping/100)
+}
+
+func (m *Mixer) GetReverbRoomSize() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.reverbRoomSize
+}
+
+// SetReverbDamping sets how quickly high frequencies die away in the reverb
+// tail (0-100).
+func (m *Mixer) SetReverbDamping(damping float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reverbDamping = math.Max(0, math.Min(100, damping))
+	m.reverb.Set(m.reverbRoomSize/100, m.reverbDamping/100)
+}
+
+func (m *Mixer) GetReverbDamping() float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.reverbDamping
+}
+
+// SetImpulseResponse loads the room correction impulse response from a WAV
+// file or a text file of FIR coefficients; see filter.LoadImpulseResponse.
+// A mono response is used for both channels, a stereo one per channel. An
+// empty path unloads it. While room correction is heard, the old response
+// fades out to the dry signal before the new one fades in.
+func (m *Mixer) SetImpulseResponse(path string) error {
+	var left, right *filter.Convolver
+	if path != "" {
+		channels, err := filter.LoadImpulseResponse(path, m.sampleRate)
+		if err != nil {
+			return err
+		}
+		sr := float64(m.sampleRate)
+		left = filter.NewConvolver(channels[0], sr)
+		right = filter.NewConvolver(channels[min(1, len(channels)-1)], sr)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.impulseResponse = path
+	if m.convolutionMix == 0 {
+		m.convolverL, m.convolverR = left, right
+		m.swapConvolvers, m.nextConvolverL, m.nextConvolverR = false, nil, nil
+		return nil
+	}
+	m.swapConvolvers = true
+	m.nextConvolverL, m.nextConvolverR = left, right
+	return nil
+}
+
+func (m *Mixer) GetImpulseResponse() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.impulseResponse
+}
+
+// SetConvolution enables or disables room correction.
+func (m *Mixer) SetConvolution(on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.convolution = on
+}
+
+func (m *Mixer) GetConvolution() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.convolution
+}
+
+// SetSleepTimer schedules the mixer to power off at deadline, fading out
+// over the configured tail. A zero deadline cancels the timer. The timer
+// only runs while the power is on, so it is ignored while off, just as
+// powering off cancels it.
+func (m *Mixer) SetSleepTimer(deadline time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.power {
+		m.timerDeadline = time.Time{}
+		return
+	}
+	m.timerDeadline = deadline
+}
+
+func (m *Mixer) GetSleepTimer() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.timerDeadline
+}
+
+// GetTimerRemaining returns the time left on the sleep timer, or 0 if none is set.
+func (m *Mixer) GetTimerRemaining() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.timerDeadline.IsZero() {
+		return 0
+	}
+	return max(0, time.Until(m.timerDeadline))
+}
+
+func (m *Mixer) SetTimerFade(fade time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timerFade = max(0, fade)
+}
+
+// timerGain returns the sleep timer fade gain at the end of a buffer of the
+// given duration, which starts the given time before the deadline. Within
+// the fade tail the gain ramps linearly from where it is to 0 at the
+// deadline, so a timer set with less than the tail left starts at full
+// volume. Otherwise it returns to 1.
+func (m *Mixer) timerGain(remaining, buffer time.Duration) float64 {
+	if m.timerDeadline.IsZero() || m.timerFade <= 0 || remaining > m.timerFade {
+		return 1
+	}
+	return m.timerLevel * float64(max(0, remaining-buffer)) / float64(remaining)
+}
+
+func (m *Mixer) ReseedRNG(seed int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, l := range m.layers {
+		l.reseed(seed + int64(i))
+	}
+	m.modRNG = rand.New(rand.NewSource(seed + int64(len(m.layers))))
+	m.evolveRNG = rand.New(rand.NewSource(seed + int64(len(m.layers)) + 1))
+}
+
+func (m *Mixer) Mix(samples int) []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]float64, samples*2)
+
+	// Sleep timer: fade out across this buffer, then power off at the deadline
+	var remaining time.Duration
+	if m.power && !m.timerDeadline.IsZero() {
+		remaining = time.Until(m.timerDeadline)
+		if remaining <= 0 {
+			m.power = false
+			m.timerDeadline = time.Time{}
+			if m.timerLevel == 0 {
+				// Already faded out by the timer, skip the power envelope
+				m.envelope = 0
+			}
+		}
+	}
+	bufferDuration := time.Duration(samples) * time.Second / time.Duration(m.sampleRate)
+	gainStart, gainEnd := m.timerLevel, m.timerGain(remaining, bufferDuration)
+	m.timerLevel = gainEnd
+
+	on := m.power && !m.draining
+	if !on && m.envelope == 0 {
+		return result
+	}
+
+	// Sum all layers
+	left := make([]float64, samples)
+	right := make([]float64, samples)
+	if len(m.modulations) > 0 || m.evolve {
+		m.modulate(samples)
+	}
+	width := math.Max(0, math.Min(100, m.stereoWidth+m.widthMod)) / 100
+	for _, l := range m.layers {
+		l.mixInto(left, right, width)
+	}
+
+	// Heartbeat sits under the noise, identical in both channels
+	if m.heartbeatLevel > 0 {
+		beat := m.heartbeat.Generate(samples, m.heartbeatLevel/100)
+		for i := range samples {
+			left[i] += beat[i]
+			right[i] += beat[i]
+		}
+	}
+
+	// Beat tone is a stereo source: binaural carriers differ between ears
+	if m.toneLevel > 0 {
+		toneL, toneR := m.tone.Generate(samples, m.toneLevel/100)
+		for i := range samples {
+			left[i] += toneL[i]
+			right[i] += toneR[i]
+		}
+	}
+
+	m.tiltL.Process(left)
+	m.tiltR.Process(right)
+	for i := range m.eq {
+		m.eqL[i].Process(left)
+		m.eqR[i].Process(right)
+	}
+	m.applyReverb(left, right)
+	m.applyConvolution(left, right)
+
+	m.applyTinnitus(left, right)
+
+	fadeInStep := m.envelopeStep(m.fadeIn)
+	fadeOutStep := m.envelopeStep(m.fadeOut)
+
+	// Apply volume with smoothing and the power envelope
+	level := math.Pow(10, m.levelMod/20)
+	targetVolume := math.Max(0, math.Min(1, m.targetVolume*level+m.volumeMod))
+	for i := range samples {
+		m.masterVolume += (targetVolume - m.masterVolume) * 0.001
+		if on {
+			m.envelope = math.Min(1, m.envelope+fadeInStep)
+		} else {
+			m.envelope = math.Max(0, m.envelope-fadeOutStep)
+		}
+		// Squared envelope gives a perceptually smoother fade than a linear ramp
+		gain := m.masterVolume * m.envelope * m.envelope * (gainStart + (gainEnd-gainStart)*float64(i)/float64(samples))
+		left[i] *= gain
+		right[i] *= gain
+	}
+
+	// Limit peaks last so EQ boosts and loud colors never hard clip
+	m.limiter.Process(left, right)
+
+	for i := range samples {
+		result[i*2] = math.Max(-1, math.Min(1, left[i]))
+		result[i*2+1] = math.Max(-1, math.Min(1, right[i]))
+	}
+
+	return result
+}
+
+// applyReverb crossfades between the dry and reverberated signal by the
+// reverb mix, skipping the reverb while it is fully dry.
+func (m *Mixer) applyReverb(left, right []float64) {
+	target := m.reverbMix / 100
+	if target == 0 && m.reverbWet < 1e-4 {
+		m.reverbWet = 0
+		return
+	}
+	if m.reverbWet == 0 {
+		// Start from silence rather than the tail from when it was last on
+		m.reverb.Reset()
+	}
+
+	wetL := append([]float64(nil), left...)
+	wetR := append([]float64(nil), right...)
+	m.reverb.Process(wetL, wetR)
+
+	for i := range left {
+		m.reverbWet += (target - m.reverbWet) * 0.001
+		left[i] += (wetL[i] - left[i]) * m.reverbWet
+		right[i] += (wetR[i] - right[i]) * m.reverbWet
+	}
+}
+
+// applyConvolution convolves the mix with the impulse response, crossfading
+// between the dry and convolved signal while room correction is switched.
+func (m *Mixer) applyConvolution(left, right []float64) {
+	if m.swapConvolvers && m.convolutionMix < 1e-4 {
+		// The new convolvers start empty, so fade them in from the dry
+		// signal
+		m.convolverL, m.convolverR = m.nextConvolverL, m.nextConvolverR
+		m.swapConvolvers, m.nextConvolverL, m.nextConvolverR = false, nil, nil
+		m.convolutionMix = 0
+	}
+	target := 0.0
+	if m.convolution && m.convolverL != nil && !m.swapConvolvers {
+		target = 1
+	}
+	if target == 0 && m.convolutionMix < 1e-4 {
+		m.convolutionMix = 0
+		return
+	}
+	if m.convolutionMix == 0 {
+		// Don't replay whatever was left from the last time it was on
+		m.convolverL.Reset()
+		m.convolverR.Reset()
+	}
+
+	wetL := append([]float64(nil), left...)
+	wetR := append([]float64(nil), right...)
+	m.convolverL.Process(wetL)
+	m.convolverR.Process(wetR)
+
+	for i := range left {
+		m.convolutionMix += (target - m.convolutionMix) * 0.001
+		left[i] += (wetL[i] - left[i]) * m.convolutionMix
+		right[i] += (wetR[i] - right[i]) * m.convolutionMix
+	}
+}
+
+// applyTinnitus notches the tinnitus band out of the mix, crossfading
+// between the dry and notched signal while the mode is switched.
+func (m *Mixer) applyTinnitus(left, right []float64) {
+	target := 0.0
+	if m.tinnitus {
+		target = 1
+	}
+	if target == 0 && m.tinnitusMix < 1e-4 {
+		m.tinnitusMix = 0
+		return
+	}
+
+	notchedL := append([]float64(nil), left...)
+	notchedR := append([]float64(nil), right...)
+	m.notchL.Process(notchedL)
+	m.notchR.Process(notchedR)
+
+	for i := range left {
+		m.tinnitusMix += (target - m.tinnitusMix) * 0.001
+		left[i] += (notchedL[i] - left[i]) * m.tinnitusMix
+		right[i] += (notchedR[i] - right[i]) * m.tinnitusMix
+	}
 }
@@ -0,0 +1,164 @@
+package mixer
+
+import (
+	"math"
+	"math/rand"
+)
+
+// LFOShape is the waveform of a modulation LFO.
+type LFOShape string
+
+const (
+	LFOSine     LFOShape = "sine"
+	LFOTriangle LFOShape = "triangle"
+	// LFORandomWalk drifts like Brownian motion, bouncing off the ends of its
+	// range; the rate sets how fast it wanders.
+	LFORandomWalk LFOShape = "random_walk"
+	// LFOSmoothRandom glides to a new random value once per cycle.
+	LFOSmoothRandom LFOShape = "smooth_random"
+)
+
+// LFOShapes lists every LFO shape.
+var LFOShapes = []LFOShape{LFOSine, LFOTriangle, LFORandomWalk, LFOSmoothRandom}
+
+// ParseLFOShape returns the LFOShape with the given name, defaulting to
+// LFOSine.
+func ParseLFOShape(name string) LFOShape {
+	for _, s := range LFOShapes {
+		if string(s) == name {
+			return s
+		}
+	}
+	return LFOSine
+}
+
+// ModTarget is a mixer parameter an LFO can modulate.
+type ModTarget string
+
+const (
+	ModVolume ModTarget = "volume"
+	ModColor  ModTarget = "color"
+	ModBass   ModTarget = "bass"
+	ModTreble ModTarget = "treble"
+	ModWidth  ModTarget = "width"
+)
+
+// ModTargets lists every modulation target.
+var ModTargets = []ModTarget{ModVolume, ModColor, ModBass, ModTreble, ModWidth}
+
+// Modulation routes one LFO to a parameter. Depth is how far the parameter
+// swings either side of its setting, in the units of its slider: volume and
+// stereo width in percent, color 0-100, bass and treble -100 to 100. Color,
+// bass and treble are modulated on every layer.
+type Modulation struct {
+	Shape  LFOShape  `json:"shape"`
+	Target ModTarget `json:"target"`
+	Rate   float64   `json:"rate"`  // Hz, 0.001-2
+	Depth  float64   `json:"depth"` // 0-100
+}
+
+// clamp returns the routing with its rate and depth limited to their ranges
+// and an unknown shape replaced by a sine.
+func (mod Modulation) clamp() Modulation {
+	mod.Shape = ParseLFOShape(string(mod.Shape))
+	mod.Rate = math.Max(0.001, math.Min(2, mod.Rate))
+	mod.Depth = math.Max(0, math.Min(100, mod.Depth))
+	return mod
+}
+
+func validTarget(target ModTarget) bool {
+	for _, t := range ModTargets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// lfo is the running state of one routing.
+type lfo struct {
+	phase float64 // 0-1 through the current cycle
+	value float64 // random walk position, -1 to 1
+	from  float64 // smooth random start and end of the current cycle
+	to    float64
+}
+
+// advance moves the LFO on by dt seconds and returns its output, -1 to 1.
+func (o *lfo) advance(shape LFOShape, rate, dt float64, rng *rand.Rand) float64 {
+	o.phase += rate * dt
+	wrapped := o.phase >= 1
+	o.phase -= math.Floor(o.phase)
+
+	switch shape {
+	case LFOTriangle:
+		return 1 - 4*math.Abs(o.phase-0.5)
+	case LFORandomWalk:
+		// Variance grows with rate·t, so one cycle wanders about the whole range
+		o.value += rng.NormFloat64() * math.Sqrt(rate*dt) * 2
+		for o.value > 1 || o.value < -1 {
+			if o.value > 1 {
+				o.value = 2 - o.value
+			} else {
+				o.value = -2 - o.value
+			}
+		}
+		return o.value
+	case LFOSmoothRandom:
+		if wrapped {
+			o.from, o.to = o.to, rng.Float64()*2-1
+		}
+		return o.from + (o.to-o.from)*(1-math.Cos(math.Pi*o.phase))/2
+	default:
+		return math.Sin(2 * math.Pi * o.phase)
+	}
+}
+
+// SetModulations replaces the modulation routings. Routings with an unknown
+// target are dropped. LFOs keep running where a routing's index already
+// existed, so tweaking one doesn't restart the others.
+func (m *Mixer) SetModulations(mods []Modulation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := make([]Modulation, 0, len(mods))
+	for _, mod := range mods {
+		if validTarget(mod.Target) {
+			kept = append(kept, mod.clamp())
+		}
+	}
+	lfos := make([]lfo, len(kept))
+	copy(lfos, m.lfos)
+	m.modulations, m.lfos = kept, lfos
+
+	if len(kept) == 0 {
+		m.modulate(0)
+	}
+}
+
+func (m *Mixer) GetModulations() []Modulation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]Modulation{}, m.modulations...)
+}
+
+// modulate advances every LFO and the evolving mode by samples and applies
+// the summed offsets. Offsets change once per buffer; the volume smoothing,
+// color glide (in both color modes), width ramp and filter coefficient glide
+// make that inaudible.
+func (m *Mixer) modulate(samples int) {
+	dt := float64(samples) / float64(m.sampleRate)
+	offsets := make(map[ModTarget]float64)
+	for i, mod := range m.modulations {
+		offsets[mod.Target] += m.lfos[i].advance(mod.Shape, mod.Rate, dt, m.modRNG) * mod.Depth
+	}
+
+	m.volumeMod = offsets[ModVolume] / 100
+	m.widthMod = offsets[ModWidth]
+	mainColor, mainBass, mainTreble := m.drift(dt)
+	for i, l := range m.layers {
+		color, bass, treble := offsets[ModColor], offsets[ModBass], offsets[ModTreble]
+		if i == 0 {
+			color, bass, treble = color+mainColor, bass+mainBass, treble+mainTreble
+		}
+		l.modulate(color, bass, treble)
+	}
+}
@@ -6,44 +6,83 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/agusx1211/pink-noise/internal/filter"
 	"github.com/agusx1211/pink-noise/internal/mixer"
+	"github.com/agusx1211/pink-noise/internal/noise"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
 type Preset struct {
-	Name   string
-	Color  float64
-	Bass   float64
-	Treble float64
+	Name      string                `json:"name"`
+	Color     float64               `json:"color"`
+	Bass      float64               `json:"bass"`
+	Treble    float64               `json:"treble"`
+	Tilt      float64               `json:"tilt,omitempty"`
+	Sound     noise.Sound           `json:"sound,omitempty"`     // empty means colored noise
+	Heartbeat float64               `json:"heartbeat,omitempty"` // heartbeat level, 0 disables it
+	Spectrum  []noise.SpectrumPoint `json:"spectrum,omitempty"`  // curve for the spectrum sound
+
+	// Shelf corners and slope; zero leaves the current setting unchanged
+	BassFrequency   float64 `json:"bass_frequency,omitempty"`
+	TrebleFrequency float64 `json:"treble_frequency,omitempty"`
+	ShelfSlope      float64 `json:"shelf_slope,omitempty"`
+
+	// Evolving mode bounds, nil for a preset that doesn't evolve. Zero drift
+	// time leaves the current setting unchanged
+	Evolve          *mixer.EvolveBounds `json:"evolve,omitempty"`
+	EvolveDriftTime float64             `json:"evolve_drift_time,omitempty"`
 }
 
 var Presets = []Preset{
-	{"Womb Sounds", 5, 80, -60},
-	{"Deep Sleep", 12, 50, -40},
-	{"Shushing", 30, -20, 30},
-	{"Fan Noise", 45, 40, -10},
-	{"Gentle Rain", 25, 10, -20},
-	{"Light Sleep", 35, 0, -30},
-	{"Calming Wash", 20, 30, -50},
-	{"Bright Comfort", 55, -10, 20},
-	{"Brown Noise", 0, 0, 0},
-	{"Pink Noise", 25, 0, 0},
-	{"White Noise", 50, 0, 0},
+	{Name: "Womb Sounds", Color: 5, Bass: 80, Treble: -60, Heartbeat: 40},
+	{Name: "Deep Sleep", Color: 12, Bass: 50, Treble: -40},
+	{Name: "Shushing", Color: 30, Bass: -20, Treble: 30, Sound: noise.SoundShush},
+	{Name: "Fan Noise", Color: 45, Bass: 40, Treble: -10},
+	{Name: "Gentle Rain", Color: 25, Bass: 10, Treble: -20, Sound: noise.SoundRain},
+	{Name: "Light Sleep", Color: 35, Bass: 0, Treble: -30},
+	{Name: "Calming Wash", Color: 20, Bass: 30, Treble: -50},
+	{Name: "Bright Comfort", Color: 55, Bass: -10, Treble: 20},
+	{Name: "Brown Noise", Color: 0, Bass: 0, Treble: 0},
+	{Name: "Pink Noise", Color: 25, Bass: 0, Treble: 0},
+	{Name: "White Noise", Color: 50, Bass: 0, Treble: 0},
+	{Name: "Ocean Waves", Color: 0, Bass: 20, Treble: -20, Sound: noise.SoundOcean},
+	{Name: "Grey Noise", Color: 50, Bass: 0, Treble: 0, Sound: noise.SoundGrey},
 }
 
+// customPresets are the presets saved over MQTT, shown after the built-in ones.
+var (
+	customPresets   []Preset
+	customPresetsMu sync.RWMutex
+)
+
 type Client struct {
 	client      mqtt.Client
 	topic       string
 	mixer       *mixer.Mixer
 	commandChan chan<- Command
+
+	// discoveryMu serializes PublishDiscovery, which runs both on connect
+	// and from the command loop, and guards the entity count it keeps
+	discoveryMu sync.Mutex
+	entities    int
 }
 
 type Command struct {
-	Action string
-	Value  float64
-	Preset string
+	Action   string
+	Value    float64
+	Preset   string
+	Sound    noise.Sound
+	Mode     noise.ColorMode
+	Tone     noise.ToneMode
+	Spectrum []noise.SpectrumPoint
+	EQ       []filter.Band
+	Routings []mixer.Modulation
+	Bounds   mixer.EvolveBounds
+	Path     string
+	Layer    int // 0-based layer index for per-layer actions
 }
 
 func NewClient(broker string, port int, user, password, topic string, m *mixer.Mixer, cmdChan chan<- Command) (*Client, error) {
@@ -85,13 +124,76 @@ func (c *Client) onConnect(client mqtt.Client) {
 	client.Publish(c.topic+"/availability", 0, true, "online")
 
 	subs := map[string]mqtt.MessageHandler{
-		c.topic + "/power/set":    c.handlePower,
-		c.topic + "/volume/set":   c.handleVolume,
-		c.topic + "/preset/set":   c.handlePreset,
-		c.topic + "/color/set":    c.handleColor,
-		c.topic + "/bass/set":     c.handleBass,
-		c.topic + "/treble/set":   c.handleTreble,
-		c.topic + "/stop_all/set": c.handleStopAll,
+		c.topic + "/power/set":     c.handlePower,
+		c.topic + "/volume/set":    c.handleVolume,
+		c.topic + "/preset/set":    c.handlePreset,
+		c.topic + "/preset/save":   c.handlePresetName("save_preset"),
+		c.topic + "/preset/delete": c.handlePresetName("delete_preset"),
+		c.topic + "/color/set":     c.handleColor,
+		c.topic + "/bass/set":      c.handleBass,
+		c.topic + "/treble/set":    c.handleTreble,
+		c.topic + "/stop_all/set":  c.handleStopAll,
+		c.topic + "/timer/set":     c.handleValue("set_timer"),
+		c.topic + "/fade_in/set":   c.handleValue("set_fade_in"),
+		c.topic + "/fade_out/set":  c.handleValue("set_fade_out"),
+		c.topic + "/layer/+/+/set": c.handleLayer,
+
+		c.topic + "/sound/set":          c.handleSound,
+		c.topic + "/color_mode/set":     c.handleColorMode,
+		c.topic + "/spectrum/set":       c.handleSpectrum,
+		c.topic + "/rain_density/set":   c.handleValue("set_rain_density"),
+		c.topic + "/rain_intensity/set": c.handleValue("set_rain_intensity"),
+
+		c.topic + "/ocean_period/set":     c.handleValue("set_ocean_period"),
+		c.topic + "/ocean_depth/set":      c.handleValue("set_ocean_depth"),
+		c.topic + "/ocean_randomness/set": c.handleValue("set_ocean_randomness"),
+
+		c.topic + "/shush_rate/set":       c.handleValue("set_shush_rate"),
+		c.topic + "/shush_duty/set":       c.handleValue("set_shush_duty"),
+		c.topic + "/shush_randomness/set": c.handleValue("set_shush_randomness"),
+
+		c.topic + "/grey_phon/set": c.handleValue("set_grey_phon"),
+
+		c.topic + "/stereo_width/set": c.handleValue("set_stereo_width"),
+
+		c.topic + "/bass_frequency/set":   c.handleValue("set_bass_frequency"),
+		c.topic + "/treble_frequency/set": c.handleValue("set_treble_frequency"),
+		c.topic + "/shelf_slope/set":      c.handleValue("set_shelf_slope"),
+
+		c.topic + "/modulation/set": c.handleModulation,
+
+		c.topic + "/evolve/set":            c.handleSwitch("set_evolve"),
+		c.topic + "/evolve_bounds/set":     c.handleEvolveBounds,
+		c.topic + "/evolve_drift_time/set": c.handleValue("set_evolve_drift_time"),
+
+		c.topic + "/tilt/set":        c.handleValue("set_tilt"),
+		c.topic + "/tilt_center/set": c.handleValue("set_tilt_center"),
+
+		c.topic + "/eq/set":       c.handleEQ,
+		c.topic + "/response/get": c.handleResponse,
+
+		c.topic + "/reverb_mix/set":       c.handleValue("set_reverb_mix"),
+		c.topic + "/reverb_room_size/set": c.handleValue("set_reverb_room_size"),
+		c.topic + "/reverb_damping/set":   c.handleValue("set_reverb_damping"),
+
+		c.topic + "/convolution/set":      c.handleSwitch("set_convolution"),
+		c.topic + "/impulse_response/set": c.handleImpulseResponse,
+
+		c.topic + "/tinnitus/set":           c.handleSwitch("set_tinnitus"),
+		c.topic + "/tinnitus_frequency/set": c.handleValue("set_tinnitus_frequency"),
+		c.topic + "/tinnitus_width/set":     c.handleValue("set_tinnitus_width"),
+
+		c.topic + "/limiter_threshold/set": c.handleValue("set_limiter_threshold"),
+		c.topic + "/limiter_release/set":   c.handleValue("set_limiter_release"),
+		c.topic + "/compressor/set":        c.handleSwitch("set_compressor"),
+
+		c.topic + "/heartbeat_bpm/set":   c.handleValue("set_heartbeat_bpm"),
+		c.topic + "/heartbeat_level/set": c.handleValue("set_heartbeat_level"),
+
+		c.topic + "/tone_mode/set":    c.handleToneMode,
+		c.topic + "/tone_carrier/set": c.handleValue("set_tone_carrier"),
+		c.topic + "/tone_beat/set":    c.handleValue("set_tone_beat"),
+		c.topic + "/tone_level/set":   c.handleValue("set_tone_level"),
 	}
 
 	for topic, handler := range subs {
@@ -101,7 +203,7 @@ func (c *Client) onConnect(client mqtt.Client) {
 	}
 
 	c.cleanupOldEntities()
-	c.publishDiscovery()
+	c.PublishDiscovery()
 	c.PublishState()
 }
 
@@ -131,6 +233,17 @@ func (c *Client) handlePreset(client mqtt.Client, msg mqtt.Message) {
 	c.sendCommand(Command{Action: "set_preset", Preset: name})
 }
 
+// handlePresetName returns a handler that forwards a preset name as the given action.
+func (c *Client) handlePresetName(action string) mqtt.MessageHandler {
+	return func(client mqtt.Client, msg mqtt.Message) {
+		name := strings.TrimSpace(string(msg.Payload()))
+		if name == "" {
+			return
+		}
+		c.sendCommand(Command{Action: action, Preset: name})
+	}
+}
+
 func (c *Client) handleColor(client mqtt.Client, msg mqtt.Message) {
 	v, err := strconv.ParseFloat(strings.TrimSpace(string(msg.Payload())), 64)
 	if err != nil {
@@ -155,6 +268,179 @@ func (c *Client) handleTreble(client mqtt.Client, msg mqtt.Message) {
 	c.sendCommand(Command{Action: "set_treble", Value: v})
 }
 
+// handleValue returns a handler that forwards a numeric payload as the given action.
+func (c *Client) handleValue(action string) mqtt.MessageHandler {
+	return func(client mqtt.Client, msg mqtt.Message) {
+		v, err := strconv.ParseFloat(strings.TrimSpace(string(msg.Payload())), 64)
+		if err != nil {
+			return
+		}
+		c.sendCommand(Command{Action: action, Value: v})
+	}
+}
+
+// handleSwitch returns a handler that forwards an ON/OFF payload as the given
+// action, with Value 1 for ON and 0 for OFF.
+func (c *Client) handleSwitch(action string) mqtt.MessageHandler {
+	return func(client mqtt.Client, msg mqtt.Message) {
+		cmd := Command{Action: action}
+		if strings.TrimSpace(string(msg.Payload())) == "ON" {
+			cmd.Value = 1
+		}
+		c.sendCommand(cmd)
+	}
+}
+
+func (c *Client) handleSound(client mqtt.Client, msg mqtt.Message) {
+	sound := noise.ParseSound(strings.TrimSpace(string(msg.Payload())))
+	c.sendCommand(Command{Action: "set_sound", Sound: sound})
+}
+
+func (c *Client) handleColorMode(client mqtt.Client, msg mqtt.Message) {
+	mode := noise.ParseColorMode(strings.TrimSpace(string(msg.Payload())))
+	c.sendCommand(Command{Action: "set_color_mode", Mode: mode})
+}
+
+func (c *Client) handleToneMode(client mqtt.Client, msg mqtt.Message) {
+	mode := noise.ParseToneMode(strings.TrimSpace(string(msg.Payload())))
+	c.sendCommand(Command{Action: "set_tone_mode", Tone: mode})
+}
+
+// handleSpectrum handles a JSON spectrum curve such as
+// [{"freq": 100, "db": 0}, {"freq": 1000, "db": -12}].
+func (c *Client) handleSpectrum(client mqtt.Client, msg mqtt.Message) {
+	points, err := parseSpectrum(msg.Payload())
+	if err != nil {
+		log.Printf("Invalid spectrum: %v", err)
+		return
+	}
+	c.sendCommand(Command{Action: "set_spectrum", Spectrum: points})
+}
+
+func parseSpectrum(payload []byte) ([]noise.SpectrumPoint, error) {
+	var points []noise.SpectrumPoint
+	if err := json.Unmarshal(payload, &points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// handleEQ handles a JSON list of parametric EQ bands such as
+// [{"type": "peaking", "freq": 1000, "q": 1.4, "gain": -6}]. An empty list
+// clears the EQ.
+func (c *Client) handleEQ(client mqtt.Client, msg mqtt.Message) {
+	var bands []filter.Band
+	if err := json.Unmarshal(msg.Payload(), &bands); err != nil {
+		log.Printf("Invalid EQ: %v", err)
+		return
+	}
+	for i, b := range bands {
+		if err := b.Validate(); err != nil {
+			log.Printf("Invalid EQ: band %d: %v", i+1, err)
+			return
+		}
+	}
+	c.sendCommand(Command{Action: "set_eq", EQ: bands})
+}
+
+// handleModulation handles a JSON list of LFO routings such as
+// [{"shape": "sine", "target": "volume", "rate": 0.1, "depth": 5}]. An
+// empty list stops all modulation.
+func (c *Client) handleModulation(client mqtt.Client, msg mqtt.Message) {
+	var mods []mixer.Modulation
+	if err := json.Unmarshal(msg.Payload(), &mods); err != nil {
+		log.Printf("Invalid modulation: %v", err)
+		return
+	}
+	c.sendCommand(Command{Action: "set_modulation", Routings: mods})
+}
+
+// handleEvolveBounds handles the JSON bounds of the evolving mode such as
+// {"color": {"min": 15, "max": 35}, "level": {"min": -4, "max": 0}}.
+// Parameters left out don't drift.
+func (c *Client) handleEvolveBounds(client mqtt.Client, msg mqtt.Message) {
+	var bounds mixer.EvolveBounds
+	if err := json.Unmarshal(msg.Payload(), &bounds); err != nil {
+		log.Printf("Invalid evolve bounds: %v", err)
+		return
+	}
+	c.sendCommand(Command{Action: "set_evolve_bounds", Bounds: bounds})
+}
+
+// handleImpulseResponse handles the path of a room correction impulse
+// response. An empty payload unloads it.
+func (c *Client) handleImpulseResponse(client mqtt.Client, msg mqtt.Message) {
+	path := strings.TrimSpace(string(msg.Payload()))
+	c.sendCommand(Command{Action: "set_impulse_response", Path: path})
+}
+
+// handleResponse publishes the current frequency response to
+// <prefix>/response. The payload is an optional JSON list of frequencies in
+// Hz; empty asks for the default sixth-octave grid.
+func (c *Client) handleResponse(client mqtt.Client, msg mqtt.Message) {
+	var freqs []float64
+	if payload := strings.TrimSpace(string(msg.Payload())); payload != "" {
+		if err := json.Unmarshal([]byte(payload), &freqs); err != nil {
+			log.Printf("Invalid response frequencies: %v", err)
+			return
+		}
+	}
+	data, _ := json.Marshal(c.mixer.Response(freqs))
+	c.client.Publish(c.topic+"/response", 0, false, data)
+}
+
+// handleLayer handles <prefix>/layer/<n>/<param>/set, where n is 1-based.
+func (c *Client) handleLayer(client mqtt.Client, msg mqtt.Message) {
+	parts := strings.Split(strings.TrimPrefix(msg.Topic(), c.topic+"/layer/"), "/")
+	if len(parts) != 3 {
+		return
+	}
+	n, err := strconv.Atoi(parts[0])
+	if err != nil || n < 1 || n > c.mixer.LayerCount() {
+		return
+	}
+
+	payload := strings.TrimSpace(string(msg.Payload()))
+	cmd := Command{Layer: n - 1}
+
+	switch parts[1] {
+	case "mute":
+		cmd.Action = "set_mute"
+		if payload == "ON" {
+			cmd.Value = 1
+		}
+	case "sound":
+		cmd.Action = "set_sound"
+		cmd.Sound = noise.ParseSound(payload)
+	case "color_mode":
+		cmd.Action = "set_color_mode"
+		cmd.Mode = noise.ParseColorMode(payload)
+	case "spectrum":
+		points, err := parseSpectrum(msg.Payload())
+		if err != nil {
+			log.Printf("Invalid spectrum: %v", err)
+			return
+		}
+		cmd.Action = "set_spectrum"
+		cmd.Spectrum = points
+	case "color", "bass", "treble", "gain", "rain_density", "rain_intensity",
+		"ocean_period", "ocean_depth", "ocean_randomness",
+		"shush_rate", "shush_duty", "shush_randomness", "grey_phon":
+		v, err := strconv.ParseFloat(payload, 64)
+		if err != nil {
+			return
+		}
+		cmd.Action = "set_" + parts[1]
+		cmd.Value = v
+		if parts[1] == "gain" {
+			cmd.Value = v / 100.0
+		}
+	default:
+		return
+	}
+	c.sendCommand(cmd)
+}
+
 func (c *Client) handleStopAll(client mqtt.Client, msg mqtt.Message) {
 	c.sendCommand(Command{Action: "stop_all"})
 }
@@ -167,7 +453,13 @@ func (c *Client) sendCommand(cmd Command) {
 	}
 }
 
-func (c *Client) publishDiscovery() {
+// PublishDiscovery publishes the Home Assistant discovery config for every
+// entity. It is called again when custom presets change the preset options.
+func (c *Client) PublishDiscovery() {
+	c.discoveryMu.Lock()
+	defer c.discoveryMu.Unlock()
+	c.entities = 0
+
 	device := map[string]interface{}{
 		"identifiers":  []string{"pink_noise_generator"},
 		"name":         "Pink Noise Generator",
@@ -181,16 +473,16 @@ func (c *Client) publishDiscovery() {
 
 	// Power switch
 	c.publishEntity("switch", "pink_noise_power", map[string]interface{}{
-		"name":            "Power",
-		"unique_id":       "pink_noise_power",
-		"device":          device,
-		"availability":    availability,
-		"command_topic":   c.topic + "/power/set",
-		"state_topic":     c.topic + "/state",
-		"value_template":  "{% if value_json.power %}ON{% else %}OFF{% endif %}",
-		"payload_on":      "ON",
-		"payload_off":     "OFF",
-		"icon":            "mdi:power",
+		"name":           "Power",
+		"unique_id":      "pink_noise_power",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/power/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{% if value_json.power %}ON{% else %}OFF{% endif %}",
+		"payload_on":     "ON",
+		"payload_off":    "OFF",
+		"icon":           "mdi:power",
 	})
 
 	// Volume number
@@ -209,9 +501,25 @@ func (c *Client) publishDiscovery() {
 		"icon":                "mdi:volume-high",
 	})
 
+	// Stereo width
+	c.publishEntity("number", "pink_noise_stereo_width", map[string]interface{}{
+		"name":                "Stereo Width",
+		"unique_id":           "pink_noise_stereo_width",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/stereo_width/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.stereo_width | round(0) }}",
+		"min":                 0,
+		"max":                 100,
+		"step":                1,
+		"unit_of_measurement": "%",
+		"icon":                "mdi:speaker-multiple",
+	})
+
 	// Preset select
 	presetOptions := make([]string, 0, len(Presets)+1)
-	for _, p := range Presets {
+	for _, p := range AllPresets() {
 		presetOptions = append(presetOptions, p.Name)
 	}
 	presetOptions = append(presetOptions, "Custom")
@@ -228,6 +536,397 @@ func (c *Client) publishDiscovery() {
 		"icon":           "mdi:baby-face",
 	})
 
+	// Saves the current main layer settings as a custom preset
+	c.publishEntity("text", "pink_noise_preset_save", map[string]interface{}{
+		"name":          "Save Preset As",
+		"unique_id":     "pink_noise_preset_save",
+		"device":        device,
+		"availability":  availability,
+		"command_topic": c.topic + "/preset/save",
+		"min":           1,
+		"max":           64,
+		"icon":          "mdi:content-save",
+	})
+
+	// Sound type select
+	soundOptions := make([]string, 0, len(noise.Sounds))
+	for _, s := range noise.Sounds {
+		soundOptions = append(soundOptions, string(s))
+	}
+
+	c.publishEntity("select", "pink_noise_sound", map[string]interface{}{
+		"name":           "Sound",
+		"unique_id":      "pink_noise_sound",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/sound/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{{ value_json.layers[0].sound }}",
+		"options":        soundOptions,
+		"icon":           "mdi:waveform",
+	})
+
+	// Color mode select
+	colorModeOptions := make([]string, 0, len(noise.ColorModes))
+	for _, m := range noise.ColorModes {
+		colorModeOptions = append(colorModeOptions, string(m))
+	}
+
+	c.publishEntity("select", "pink_noise_color_mode", map[string]interface{}{
+		"name":           "Color Mode",
+		"unique_id":      "pink_noise_color_mode",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/color_mode/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{{ value_json.layers[0].color_mode }}",
+		"options":        colorModeOptions,
+		"icon":           "mdi:chart-line-variant",
+	})
+
+	// Rain sliders
+	c.publishEntity("number", "pink_noise_rain_density", map[string]interface{}{
+		"name":           "Rain Density",
+		"unique_id":      "pink_noise_rain_density",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/rain_density/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{{ value_json.layers[0].rain_density | round(0) }}",
+		"min":            0,
+		"max":            100,
+		"step":           1,
+		"icon":           "mdi:weather-rainy",
+	})
+
+	c.publishEntity("number", "pink_noise_rain_intensity", map[string]interface{}{
+		"name":           "Rain Intensity",
+		"unique_id":      "pink_noise_rain_intensity",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/rain_intensity/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{{ value_json.layers[0].rain_intensity | round(0) }}",
+		"min":            0,
+		"max":            100,
+		"step":           1,
+		"icon":           "mdi:weather-pouring",
+	})
+
+	// Ocean sliders
+	c.publishEntity("number", "pink_noise_ocean_period", map[string]interface{}{
+		"name":                "Ocean Wave Period",
+		"unique_id":           "pink_noise_ocean_period",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/ocean_period/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.layers[0].ocean_period }}",
+		"min":                 2,
+		"max":                 30,
+		"step":                0.5,
+		"unit_of_measurement": "s",
+		"icon":                "mdi:waves",
+	})
+
+	c.publishEntity("number", "pink_noise_ocean_depth", map[string]interface{}{
+		"name":           "Ocean Wave Depth",
+		"unique_id":      "pink_noise_ocean_depth",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/ocean_depth/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{{ value_json.layers[0].ocean_depth | round(0) }}",
+		"min":            0,
+		"max":            100,
+		"step":           1,
+		"icon":           "mdi:wave",
+	})
+
+	c.publishEntity("number", "pink_noise_ocean_randomness", map[string]interface{}{
+		"name":           "Ocean Wave Randomness",
+		"unique_id":      "pink_noise_ocean_randomness",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/ocean_randomness/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{{ value_json.layers[0].ocean_randomness | round(0) }}",
+		"min":            0,
+		"max":            100,
+		"step":           1,
+		"icon":           "mdi:dice-multiple",
+	})
+
+	// Shush sliders
+	c.publishEntity("number", "pink_noise_shush_rate", map[string]interface{}{
+		"name":                "Shush Rate",
+		"unique_id":           "pink_noise_shush_rate",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/shush_rate/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.layers[0].shush_rate | round(0) }}",
+		"min":                 10,
+		"max":                 120,
+		"step":                1,
+		"unit_of_measurement": "/min",
+		"icon":                "mdi:metronome",
+	})
+
+	c.publishEntity("number", "pink_noise_shush_duty", map[string]interface{}{
+		"name":                "Shush Duty Cycle",
+		"unique_id":           "pink_noise_shush_duty",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/shush_duty/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.layers[0].shush_duty | round(0) }}",
+		"min":                 10,
+		"max":                 90,
+		"step":                1,
+		"unit_of_measurement": "%",
+		"icon":                "mdi:square-wave",
+	})
+
+	c.publishEntity("number", "pink_noise_shush_randomness", map[string]interface{}{
+		"name":           "Shush Randomness",
+		"unique_id":      "pink_noise_shush_randomness",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/shush_randomness/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{{ value_json.layers[0].shush_randomness | round(0) }}",
+		"min":            0,
+		"max":            100,
+		"step":           1,
+		"icon":           "mdi:dice-multiple",
+	})
+
+	// Grey noise loudness level
+	c.publishEntity("number", "pink_noise_grey_phon", map[string]interface{}{
+		"name":                "Grey Loudness Level",
+		"unique_id":           "pink_noise_grey_phon",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/grey_phon/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.layers[0].grey_phon | round(0) }}",
+		"min":                 20,
+		"max":                 80,
+		"step":                5,
+		"unit_of_measurement": "phon",
+		"icon":                "mdi:ear-hearing",
+	})
+
+	// Reverb
+	c.publishEntity("number", "pink_noise_reverb_mix", map[string]interface{}{
+		"name":                "Reverb Mix",
+		"unique_id":           "pink_noise_reverb_mix",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/reverb_mix/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.reverb_mix | round(0) }}",
+		"min":                 0,
+		"max":                 100,
+		"step":                1,
+		"unit_of_measurement": "%",
+		"icon":                "mdi:waves",
+	})
+
+	c.publishEntity("number", "pink_noise_reverb_room_size", map[string]interface{}{
+		"name":           "Reverb Room Size",
+		"unique_id":      "pink_noise_reverb_room_size",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/reverb_room_size/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{{ value_json.reverb_room_size | round(0) }}",
+		"min":            0,
+		"max":            100,
+		"step":           1,
+		"icon":           "mdi:floor-plan",
+	})
+
+	c.publishEntity("number", "pink_noise_reverb_damping", map[string]interface{}{
+		"name":           "Reverb Damping",
+		"unique_id":      "pink_noise_reverb_damping",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/reverb_damping/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{{ value_json.reverb_damping | round(0) }}",
+		"min":            0,
+		"max":            100,
+		"step":           1,
+		"icon":           "mdi:blur",
+	})
+
+	// Room correction
+	c.publishEntity("switch", "pink_noise_convolution", map[string]interface{}{
+		"name":           "Room Correction",
+		"unique_id":      "pink_noise_convolution",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/convolution/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{% if value_json.convolution %}ON{% else %}OFF{% endif %}",
+		"payload_on":     "ON",
+		"payload_off":    "OFF",
+		"icon":           "mdi:home-sound-out",
+	})
+
+	c.publishEntity("text", "pink_noise_impulse_response", map[string]interface{}{
+		"name":            "Impulse Response",
+		"unique_id":       "pink_noise_impulse_response",
+		"device":          device,
+		"availability":    availability,
+		"command_topic":   c.topic + "/impulse_response/set",
+		"state_topic":     c.topic + "/state",
+		"value_template":  "{{ value_json.impulse_response }}",
+		"min":             0,
+		"max":             255,
+		"entity_category": "config",
+		"icon":            "mdi:file-music",
+	})
+
+	// Tinnitus relief
+	c.publishEntity("switch", "pink_noise_tinnitus", map[string]interface{}{
+		"name":           "Tinnitus Relief",
+		"unique_id":      "pink_noise_tinnitus",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/tinnitus/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{% if value_json.tinnitus %}ON{% else %}OFF{% endif %}",
+		"payload_on":     "ON",
+		"payload_off":    "OFF",
+		"icon":           "mdi:ear-hearing-off",
+	})
+
+	c.publishEntity("number", "pink_noise_tinnitus_frequency", map[string]interface{}{
+		"name":                "Tinnitus Frequency",
+		"unique_id":           "pink_noise_tinnitus_frequency",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/tinnitus_frequency/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.tinnitus_frequency | round(0) }}",
+		"min":                 250,
+		"max":                 12000,
+		"step":                50,
+		"mode":                "box",
+		"unit_of_measurement": "Hz",
+		"icon":                "mdi:sine-wave",
+	})
+
+	c.publishEntity("number", "pink_noise_tinnitus_width", map[string]interface{}{
+		"name":                "Tinnitus Notch Width",
+		"unique_id":           "pink_noise_tinnitus_width",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/tinnitus_width/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.tinnitus_width }}",
+		"min":                 0.25,
+		"max":                 2,
+		"step":                0.05,
+		"unit_of_measurement": "oct",
+		"icon":                "mdi:arrow-expand-horizontal",
+	})
+
+	// Heartbeat sliders
+	c.publishEntity("number", "pink_noise_heartbeat_level", map[string]interface{}{
+		"name":           "Heartbeat Level",
+		"unique_id":      "pink_noise_heartbeat_level",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/heartbeat_level/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{{ value_json.heartbeat_level | round(0) }}",
+		"min":            0,
+		"max":            100,
+		"step":           1,
+		"icon":           "mdi:heart-pulse",
+	})
+
+	c.publishEntity("number", "pink_noise_heartbeat_bpm", map[string]interface{}{
+		"name":                "Heartbeat Rate",
+		"unique_id":           "pink_noise_heartbeat_bpm",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/heartbeat_bpm/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.heartbeat_bpm | round(0) }}",
+		"min":                 40,
+		"max":                 140,
+		"step":                1,
+		"unit_of_measurement": "bpm",
+		"icon":                "mdi:heart",
+	})
+
+	// Beat tone
+	toneModeOptions := make([]string, 0, len(noise.ToneModes))
+	for _, m := range noise.ToneModes {
+		toneModeOptions = append(toneModeOptions, string(m))
+	}
+
+	c.publishEntity("select", "pink_noise_tone_mode", map[string]interface{}{
+		"name":           "Tone Mode",
+		"unique_id":      "pink_noise_tone_mode",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/tone_mode/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{{ value_json.tone_mode }}",
+		"options":        toneModeOptions,
+		"icon":           "mdi:headphones",
+	})
+
+	c.publishEntity("number", "pink_noise_tone_level", map[string]interface{}{
+		"name":           "Tone Level",
+		"unique_id":      "pink_noise_tone_level",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/tone_level/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{{ value_json.tone_level | round(0) }}",
+		"min":            0,
+		"max":            100,
+		"step":           1,
+		"icon":           "mdi:volume-medium",
+	})
+
+	c.publishEntity("number", "pink_noise_tone_carrier", map[string]interface{}{
+		"name":                "Tone Carrier",
+		"unique_id":           "pink_noise_tone_carrier",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/tone_carrier/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.tone_carrier | round(0) }}",
+		"min":                 50,
+		"max":                 1000,
+		"step":                1,
+		"unit_of_measurement": "Hz",
+		"icon":                "mdi:sine-wave",
+	})
+
+	c.publishEntity("number", "pink_noise_tone_beat", map[string]interface{}{
+		"name":                "Tone Beat Frequency",
+		"unique_id":           "pink_noise_tone_beat",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/tone_beat/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.tone_beat }}",
+		"min":                 0.5,
+		"max":                 40,
+		"step":                0.5,
+		"unit_of_measurement": "Hz",
+		"icon":                "mdi:pulse",
+	})
+
 	// Color slider
 	c.publishEntity("number", "pink_noise_color", map[string]interface{}{
 		"name":           "Color",
@@ -273,6 +972,234 @@ func (c *Client) publishDiscovery() {
 		"icon":           "mdi:music-clef-treble",
 	})
 
+	// Tilt: one darker/brighter knob for the whole sound
+	c.publishEntity("number", "pink_noise_tilt", map[string]interface{}{
+		"name":           "Tilt",
+		"unique_id":      "pink_noise_tilt",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/tilt/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{{ value_json.tilt | round(0) }}",
+		"min":            -100,
+		"max":            100,
+		"step":           1,
+		"icon":           "mdi:tune-vertical",
+	})
+
+	c.publishEntity("number", "pink_noise_tilt_center", map[string]interface{}{
+		"name":                "Tilt Center",
+		"unique_id":           "pink_noise_tilt_center",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/tilt_center/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.tilt_center | round(0) }}",
+		"min":                 200,
+		"max":                 5000,
+		"step":                50,
+		"unit_of_measurement": "Hz",
+		"entity_category":     "config",
+		"icon":                "mdi:sine-wave",
+	})
+
+	// Evolving mode: slow random drift within the bounds set over MQTT
+	c.publishEntity("switch", "pink_noise_evolve", map[string]interface{}{
+		"name":           "Evolve",
+		"unique_id":      "pink_noise_evolve",
+		"device":         device,
+		"availability":   availability,
+		"command_topic":  c.topic + "/evolve/set",
+		"state_topic":    c.topic + "/state",
+		"value_template": "{% if value_json.evolve %}ON{% else %}OFF{% endif %}",
+		"payload_on":     "ON",
+		"payload_off":    "OFF",
+		"icon":           "mdi:shuffle-variant",
+	})
+
+	c.publishEntity("number", "pink_noise_evolve_drift_time", map[string]interface{}{
+		"name":                "Evolve Drift Time",
+		"unique_id":           "pink_noise_evolve_drift_time",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/evolve_drift_time/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.evolve_drift_time | round(0) }}",
+		"min":                 1,
+		"max":                 240,
+		"step":                1,
+		"unit_of_measurement": "min",
+		"entity_category":     "config",
+		"icon":                "mdi:timer-sand",
+	})
+
+	// Shelf corners and slope
+	c.publishEntity("number", "pink_noise_bass_frequency", map[string]interface{}{
+		"name":                "Bass Frequency",
+		"unique_id":           "pink_noise_bass_frequency",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/bass_frequency/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.bass_frequency | round(0) }}",
+		"min":                 40,
+		"max":                 1000,
+		"step":                10,
+		"unit_of_measurement": "Hz",
+		"entity_category":     "config",
+		"icon":                "mdi:sine-wave",
+	})
+
+	c.publishEntity("number", "pink_noise_treble_frequency", map[string]interface{}{
+		"name":                "Treble Frequency",
+		"unique_id":           "pink_noise_treble_frequency",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/treble_frequency/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.treble_frequency | round(0) }}",
+		"min":                 1000,
+		"max":                 16000,
+		"step":                100,
+		"unit_of_measurement": "Hz",
+		"entity_category":     "config",
+		"icon":                "mdi:sine-wave",
+	})
+
+	c.publishEntity("number", "pink_noise_shelf_slope", map[string]interface{}{
+		"name":            "Shelf Slope",
+		"unique_id":       "pink_noise_shelf_slope",
+		"device":          device,
+		"availability":    availability,
+		"command_topic":   c.topic + "/shelf_slope/set",
+		"state_topic":     c.topic + "/state",
+		"value_template":  "{{ value_json.shelf_slope }}",
+		"min":             0.1,
+		"max":             1,
+		"step":            0.05,
+		"entity_category": "config",
+		"icon":            "mdi:slope-uphill",
+	})
+
+	// Sleep timer
+	c.publishEntity("number", "pink_noise_sleep_timer", map[string]interface{}{
+		"name":                "Sleep Timer",
+		"unique_id":           "pink_noise_sleep_timer",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/timer/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.timer_remaining | round(0, 'ceil') }}",
+		"min":                 0,
+		"max":                 240,
+		"step":                5,
+		"unit_of_measurement": "min",
+		"icon":                "mdi:timer-outline",
+	})
+
+	c.publishEntity("sensor", "pink_noise_timer_remaining", map[string]interface{}{
+		"name":                "Sleep Timer Remaining",
+		"unique_id":           "pink_noise_timer_remaining",
+		"device":              device,
+		"availability":        availability,
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.timer_remaining | round(1) }}",
+		"unit_of_measurement": "min",
+		"icon":                "mdi:timer-sand",
+	})
+
+	// Power fade envelope
+	c.publishEntity("number", "pink_noise_fade_in", map[string]interface{}{
+		"name":                "Fade In",
+		"unique_id":           "pink_noise_fade_in",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/fade_in/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.fade_in }}",
+		"min":                 0,
+		"max":                 60,
+		"step":                0.5,
+		"unit_of_measurement": "s",
+		"entity_category":     "config",
+		"icon":                "mdi:volume-plus",
+	})
+
+	c.publishEntity("number", "pink_noise_fade_out", map[string]interface{}{
+		"name":                "Fade Out",
+		"unique_id":           "pink_noise_fade_out",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/fade_out/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.fade_out }}",
+		"min":                 0,
+		"max":                 60,
+		"step":                0.5,
+		"unit_of_measurement": "s",
+		"entity_category":     "config",
+		"icon":                "mdi:volume-minus",
+	})
+
+	// Limiter and compressor
+	c.publishEntity("number", "pink_noise_limiter_threshold", map[string]interface{}{
+		"name":                "Limiter Threshold",
+		"unique_id":           "pink_noise_limiter_threshold",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/limiter_threshold/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.limiter_threshold }}",
+		"min":                 -24,
+		"max":                 0,
+		"step":                0.5,
+		"unit_of_measurement": "dB",
+		"entity_category":     "config",
+		"icon":                "mdi:arrow-collapse-up",
+	})
+
+	c.publishEntity("number", "pink_noise_limiter_release", map[string]interface{}{
+		"name":                "Limiter Release",
+		"unique_id":           "pink_noise_limiter_release",
+		"device":              device,
+		"availability":        availability,
+		"command_topic":       c.topic + "/limiter_release/set",
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.limiter_release | round(0) }}",
+		"min":                 10,
+		"max":                 2000,
+		"step":                10,
+		"unit_of_measurement": "ms",
+		"entity_category":     "config",
+		"icon":                "mdi:timer-sand",
+	})
+
+	c.publishEntity("switch", "pink_noise_compressor", map[string]interface{}{
+		"name":            "Compressor",
+		"unique_id":       "pink_noise_compressor",
+		"device":          device,
+		"availability":    availability,
+		"command_topic":   c.topic + "/compressor/set",
+		"state_topic":     c.topic + "/state",
+		"value_template":  "{% if value_json.compressor %}ON{% else %}OFF{% endif %}",
+		"payload_on":      "ON",
+		"payload_off":     "OFF",
+		"entity_category": "config",
+		"icon":            "mdi:arrow-collapse-vertical",
+	})
+
+	c.publishEntity("sensor", "pink_noise_gain_reduction", map[string]interface{}{
+		"name":                "Gain Reduction",
+		"unique_id":           "pink_noise_gain_reduction",
+		"device":              device,
+		"availability":        availability,
+		"state_topic":         c.topic + "/state",
+		"value_template":      "{{ value_json.gain_reduction | round(1) }}",
+		"unit_of_measurement": "dB",
+		"entity_category":     "diagnostic",
+		"icon":                "mdi:chart-bell-curve",
+	})
+
 	// Stop All button
 	c.publishEntity("button", "pink_noise_stop_all", map[string]interface{}{
 		"name":          "Stop All",
@@ -283,10 +1210,124 @@ func (c *Client) publishDiscovery() {
 		"icon":          "mdi:stop",
 	})
 
-	log.Println("Published MQTT discovery (7 entities)")
+	c.publishLayerDiscovery(device, availability, soundOptions, colorModeOptions)
+
+	log.Printf("Published MQTT discovery (%d entities)", c.entities)
+}
+
+// publishLayerDiscovery publishes gain and mute for every layer, plus color
+// and EQ for the extra layers (layer 1 uses the top-level controls).
+func (c *Client) publishLayerDiscovery(device, availability map[string]interface{}, soundOptions, colorModeOptions []string) {
+	for n := 1; n <= c.mixer.LayerCount(); n++ {
+		id := fmt.Sprintf("pink_noise_layer_%d", n)
+		name := fmt.Sprintf("Layer %d", n)
+		topic := fmt.Sprintf("%s/layer/%d", c.topic, n)
+		value := fmt.Sprintf("value_json.layers[%d]", n-1)
+
+		c.publishEntity("number", id+"_gain", map[string]interface{}{
+			"name":                name + " Gain",
+			"unique_id":           id + "_gain",
+			"device":              device,
+			"availability":        availability,
+			"command_topic":       topic + "/gain/set",
+			"state_topic":         c.topic + "/state",
+			"value_template":      "{{ (" + value + ".gain * 100) | round(0) }}",
+			"min":                 0,
+			"max":                 100,
+			"step":                1,
+			"unit_of_measurement": "%",
+			"icon":                "mdi:tune-vertical",
+		})
+
+		c.publishEntity("switch", id+"_mute", map[string]interface{}{
+			"name":           name + " Mute",
+			"unique_id":      id + "_mute",
+			"device":         device,
+			"availability":   availability,
+			"command_topic":  topic + "/mute/set",
+			"state_topic":    c.topic + "/state",
+			"value_template": "{% if " + value + ".mute %}ON{% else %}OFF{% endif %}",
+			"payload_on":     "ON",
+			"payload_off":    "OFF",
+			"icon":           "mdi:volume-off",
+		})
+
+		if n == 1 {
+			continue
+		}
+
+		c.publishEntity("select", id+"_sound", map[string]interface{}{
+			"name":           name + " Sound",
+			"unique_id":      id + "_sound",
+			"device":         device,
+			"availability":   availability,
+			"command_topic":  topic + "/sound/set",
+			"state_topic":    c.topic + "/state",
+			"value_template": "{{ " + value + ".sound }}",
+			"options":        soundOptions,
+			"icon":           "mdi:waveform",
+		})
+
+		c.publishEntity("select", id+"_color_mode", map[string]interface{}{
+			"name":           name + " Color Mode",
+			"unique_id":      id + "_color_mode",
+			"device":         device,
+			"availability":   availability,
+			"command_topic":  topic + "/color_mode/set",
+			"state_topic":    c.topic + "/state",
+			"value_template": "{{ " + value + ".color_mode }}",
+			"options":        colorModeOptions,
+			"icon":           "mdi:chart-line-variant",
+		})
+
+		c.publishEntity("number", id+"_color", map[string]interface{}{
+			"name":           name + " Color",
+			"unique_id":      id + "_color",
+			"device":         device,
+			"availability":   availability,
+			"command_topic":  topic + "/color/set",
+			"state_topic":    c.topic + "/state",
+			"value_template": "{{ " + value + ".color | round(0) }}",
+			"min":            0,
+			"max":            100,
+			"step":           1,
+			"icon":           "mdi:palette",
+		})
+
+		c.publishEntity("number", id+"_bass", map[string]interface{}{
+			"name":           name + " Bass",
+			"unique_id":      id + "_bass",
+			"device":         device,
+			"availability":   availability,
+			"command_topic":  topic + "/bass/set",
+			"state_topic":    c.topic + "/state",
+			"value_template": "{{ " + value + ".bass | round(0) }}",
+			"min":            -100,
+			"max":            100,
+			"step":           1,
+			"icon":           "mdi:music-clef-bass",
+		})
+
+		c.publishEntity("number", id+"_treble", map[string]interface{}{
+			"name":           name + " Treble",
+			"unique_id":      id + "_treble",
+			"device":         device,
+			"availability":   availability,
+			"command_topic":  topic + "/treble/set",
+			"state_topic":    c.topic + "/state",
+			"value_template": "{{ " + value + ".treble | round(0) }}",
+			"min":            -100,
+			"max":            100,
+			"step":           1,
+			"icon":           "mdi:music-clef-treble",
+		})
+	}
 }
 
 func (c *Client) publishEntity(domain, entityID string, config map[string]interface{}) {
+	if config != nil {
+		c.entities++
+	}
 	data, _ := json.Marshal(config)
 	topic := fmt.Sprintf("homeassistant/%s/%s/config", domain, entityID)
 	if token := c.client.Publish(topic, 0, true, data); token.Wait() && token.Error() != nil {
@@ -312,9 +1353,81 @@ type publishedState struct {
 	Power  bool    `json:"power"`
 	Volume float64 `json:"volume"`
 	Preset string  `json:"preset"`
+
+	StereoWidth float64 `json:"stereo_width"`
+
 	Color  float64 `json:"color"`
 	Bass   float64 `json:"bass"`
 	Treble float64 `json:"treble"`
+
+	BassFrequency   float64 `json:"bass_frequency"`
+	TrebleFrequency float64 `json:"treble_frequency"`
+	ShelfSlope      float64 `json:"shelf_slope"`
+
+	Tilt       float64 `json:"tilt"`
+	TiltCenter float64 `json:"tilt_center"`
+
+	TimerRemaining float64 `json:"timer_remaining"` // minutes
+	FadeIn         float64 `json:"fade_in"`
+	FadeOut        float64 `json:"fade_out"`
+	HeartbeatBPM   float64 `json:"heartbeat_bpm"`
+	HeartbeatLevel float64 `json:"heartbeat_level"`
+
+	ToneMode    string  `json:"tone_mode"`
+	ToneCarrier float64 `json:"tone_carrier"`
+	ToneBeat    float64 `json:"tone_beat"`
+	ToneLevel   float64 `json:"tone_level"`
+
+	LimiterThreshold float64 `json:"limiter_threshold"`
+	LimiterRelease   float64 `json:"limiter_release"`
+	Compressor       bool    `json:"compressor"`
+	GainReduction    float64 `json:"gain_reduction"`
+
+	Tinnitus          bool    `json:"tinnitus"`
+	TinnitusFrequency float64 `json:"tinnitus_frequency"`
+	TinnitusWidth     float64 `json:"tinnitus_width"`
+
+	EQ         []filter.Band      `json:"eq"`
+	Modulation []mixer.Modulation `json:"modulation"`
+
+	Evolve          bool               `json:"evolve"`
+	EvolveBounds    mixer.EvolveBounds `json:"evolve_bounds"`
+	EvolveDriftTime float64            `json:"evolve_drift_time"` // minutes
+	Evolved         mixer.Evolved      `json:"evolved"`
+
+	ReverbMix      float64 `json:"reverb_mix"`
+	ReverbRoomSize float64 `json:"reverb_room_size"`
+	ReverbDamping  float64 `json:"reverb_damping"`
+
+	Convolution     bool   `json:"convolution"`
+	ImpulseResponse string `json:"impulse_response"`
+
+	Layers []publishedLayer `json:"layers"`
+}
+
+type publishedLayer struct {
+	Sound     string  `json:"sound"`
+	ColorMode string  `json:"color_mode"`
+	Color     float64 `json:"color"`
+	Bass      float64 `json:"bass"`
+	Treble    float64 `json:"treble"`
+	Gain      float64 `json:"gain"`
+	Mute      bool    `json:"mute"`
+
+	RainDensity   float64 `json:"rain_density"`
+	RainIntensity float64 `json:"rain_intensity"`
+
+	OceanPeriod     float64 `json:"ocean_period"`
+	OceanDepth      float64 `json:"ocean_depth"`
+	OceanRandomness float64 `json:"ocean_randomness"`
+
+	ShushRate       float64 `json:"shush_rate"`
+	ShushDuty       float64 `json:"shush_duty"`
+	ShushRandomness float64 `json:"shush_randomness"`
+
+	GreyPhon float64 `json:"grey_phon"`
+
+	Spectrum []noise.SpectrumPoint `json:"spectrum,omitempty"`
 }
 
 // CurrentPreset is maintained by main.go and passed here for state publishing.
@@ -328,6 +1441,77 @@ func (c *Client) PublishState() {
 		Color:  c.mixer.GetColor(),
 		Bass:   c.mixer.GetBass(),
 		Treble: c.mixer.GetTreble(),
+
+		StereoWidth: c.mixer.GetStereoWidth(),
+
+		BassFrequency:   c.mixer.GetBassFrequency(),
+		TrebleFrequency: c.mixer.GetTrebleFrequency(),
+		ShelfSlope:      c.mixer.GetShelfSlope(),
+
+		Tilt:       c.mixer.GetTilt(),
+		TiltCenter: c.mixer.GetTiltCenter(),
+
+		TimerRemaining: c.mixer.GetTimerRemaining().Minutes(),
+		FadeIn:         c.mixer.GetFadeIn(),
+		FadeOut:        c.mixer.GetFadeOut(),
+		HeartbeatBPM:   c.mixer.GetHeartbeatBPM(),
+		HeartbeatLevel: c.mixer.GetHeartbeatLevel(),
+
+		ToneMode:    string(c.mixer.GetToneMode()),
+		ToneCarrier: c.mixer.GetToneCarrier(),
+		ToneBeat:    c.mixer.GetToneBeat(),
+		ToneLevel:   c.mixer.GetToneLevel(),
+
+		LimiterThreshold: c.mixer.GetLimiterThreshold(),
+		LimiterRelease:   c.mixer.GetLimiterRelease(),
+		Compressor:       c.mixer.GetCompressor(),
+		GainReduction:    c.mixer.GetGainReduction(),
+
+		Tinnitus:          c.mixer.GetTinnitus(),
+		TinnitusFrequency: c.mixer.GetTinnitusFrequency(),
+		TinnitusWidth:     c.mixer.GetTinnitusWidth(),
+
+		EQ:         c.mixer.GetEQ(),
+		Modulation: c.mixer.GetModulations(),
+
+		Evolve:          c.mixer.GetEvolve(),
+		EvolveBounds:    c.mixer.GetEvolveBounds(),
+		EvolveDriftTime: c.mixer.GetEvolveDriftTime(),
+		Evolved:         c.mixer.GetEvolved(),
+
+		ReverbMix:      c.mixer.GetReverbMix(),
+		ReverbRoomSize: c.mixer.GetReverbRoomSize(),
+		ReverbDamping:  c.mixer.GetReverbDamping(),
+
+		Convolution:     c.mixer.GetConvolution(),
+		ImpulseResponse: c.mixer.GetImpulseResponse(),
+	}
+
+	for n := range c.mixer.LayerCount() {
+		state.Layers = append(state.Layers, publishedLayer{
+			Sound:     string(c.mixer.GetLayerSound(n)),
+			ColorMode: string(c.mixer.GetLayerColorMode(n)),
+			Color:     c.mixer.GetLayerColor(n),
+			Bass:      c.mixer.GetLayerBass(n),
+			Treble:    c.mixer.GetLayerTreble(n),
+			Gain:      c.mixer.GetLayerGain(n),
+			Mute:      c.mixer.GetLayerMute(n),
+
+			RainDensity:   c.mixer.GetLayerRainDensity(n),
+			RainIntensity: c.mixer.GetLayerRainIntensity(n),
+
+			OceanPeriod:     c.mixer.GetLayerOceanPeriod(n),
+			OceanDepth:      c.mixer.GetLayerOceanDepth(n),
+			OceanRandomness: c.mixer.GetLayerOceanRandomness(n),
+
+			ShushRate:       c.mixer.GetLayerShushRate(n),
+			ShushDuty:       c.mixer.GetLayerShushDuty(n),
+			ShushRandomness: c.mixer.GetLayerShushRandomness(n),
+
+			GreyPhon: c.mixer.GetLayerGreyPhon(n),
+
+			Spectrum: c.mixer.GetLayerSpectrum(n),
+		})
 	}
 
 	data, _ := json.Marshal(state)
@@ -347,5 +1531,79 @@ func FindPreset(name string) *Preset {
 			return &Presets[i]
 		}
 	}
+	customPresetsMu.RLock()
+	defer customPresetsMu.RUnlock()
+	for _, p := range customPresets {
+		if p.Name == name {
+			return &p
+		}
+	}
 	return nil
 }
+
+// AllPresets returns the built-in presets followed by the custom ones.
+func AllPresets() []Preset {
+	customPresetsMu.RLock()
+	defer customPresetsMu.RUnlock()
+	all := append([]Preset(nil), Presets...)
+	return append(all, customPresets...)
+}
+
+// CustomPresets returns a copy of the presets saved over MQTT.
+func CustomPresets() []Preset {
+	customPresetsMu.RLock()
+	defer customPresetsMu.RUnlock()
+	return append([]Preset(nil), customPresets...)
+}
+
+// SetCustomPresets replaces the custom presets, e.g. when restoring state.
+func SetCustomPresets(presets []Preset) {
+	customPresetsMu.Lock()
+	defer customPresetsMu.Unlock()
+	customPresets = nil
+	for _, p := range presets {
+		if p.Name != "" && p.Name != "Custom" && !isBuiltinPreset(p.Name) {
+			customPresets = append(customPresets, p)
+		}
+	}
+}
+
+// SaveCustomPreset adds a custom preset, replacing one with the same name.
+// Built-in presets and the name "Custom" can't be overwritten.
+func SaveCustomPreset(p Preset) error {
+	if p.Name == "Custom" || isBuiltinPreset(p.Name) {
+		return fmt.Errorf("preset %q is reserved", p.Name)
+	}
+	customPresetsMu.Lock()
+	defer customPresetsMu.Unlock()
+	for i := range customPresets {
+		if customPresets[i].Name == p.Name {
+			customPresets[i] = p
+			return nil
+		}
+	}
+	customPresets = append(customPresets, p)
+	return nil
+}
+
+// DeleteCustomPreset removes a custom preset and reports whether it existed.
+func DeleteCustomPreset(name string) bool {
+	customPresetsMu.Lock()
+	defer customPresetsMu.Unlock()
+	for i := range customPresets {
+		if customPresets[i].Name == name {
+			customPresets = append(customPresets[:i], customPresets[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func isBuiltinPreset(name string) bool {
+	for _, p := range Presets {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
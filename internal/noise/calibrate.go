@@ -0,0 +1,89 @@
+package noise
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/agusx1211/pink-noise/internal/filter"
+)
+
+// targetLoudness is the K-weighted RMS every color is calibrated to, so
+// moving the color slider never changes perceived loudness. It leaves
+// headroom for the +12 dB EQ shelves.
+const targetLoudness = 0.25
+
+// colorPower returns the power response |H(f)|² of the raw filter that
+// shapes white noise into the given color.
+func (g *Generator) colorPower(color Color, f float64) float64 {
+	w := 2 * math.Pi * f / float64(g.sampleRate)
+	zInv := cmplx.Exp(complex(0, -w))
+
+	var h complex128
+	limit := 1.0
+	switch color {
+	case Pink:
+		for _, c := range g.pinkCoeffs {
+			h += complex(c, 0) / (1 - complex(1-c, 0)*zInv)
+		}
+	case Brown:
+		h = complex(g.brownInput, 0) / (1 - complex(g.brownFeedback, 0)*zInv)
+	case Blue:
+		h = 1 - zInv
+		limit = filter.CascadeResponse(g.blueLimit, f)
+	case Violet:
+		h = (1 - zInv) * (1 - zInv)
+		limit = filter.CascadeResponse(g.violetLimit, f)
+	default:
+		h = 1
+	}
+	return (real(h)*real(h) + imag(h)*imag(h)) * limit * limit
+}
+
+// kWeight returns the power response of an analog approximation of the
+// ITU-R BS.1770 K-weighting curve: a +4 dB high shelf around 1.5 kHz and a
+// second-order high-pass at 38 Hz.
+func kWeight(f float64) float64 {
+	const shelfGain = 1.585 // +4 dB
+	r := f / 1500
+	shelf := (1 + shelfGain*shelfGain*r*r) / (1 + r*r)
+
+	const hpQ = 0.5
+	r = f / 38
+	highpass := r * r * r * r / ((1-r*r)*(1-r*r) + r*r/(hpQ*hpQ))
+
+	return shelf * highpass
+}
+
+// loudness returns the K-weighted RMS of uniform white noise in [-1, 1]
+// shaped by the given power response, integrated over 20 Hz-20 kHz.
+func (g *Generator) loudness(power func(f float64) float64) float64 {
+	const points = 400
+	nyquist := float64(g.sampleRate) / 2
+	lo, hi := math.Log(20.0), math.Log(math.Min(20000, nyquist*0.999))
+
+	// Trapezoid rule over log frequency: ∫ P(f) df = ∫ P(f) f d(ln f)
+	var sum float64
+	step := (hi - lo) / points
+	for i := 0; i <= points; i++ {
+		f := math.Exp(lo + float64(i)*step)
+		v := power(f) * kWeight(f) * f
+		if i == 0 || i == points {
+			v /= 2
+		}
+		sum += v * step
+	}
+
+	// White noise variance 1/3, spread evenly up to Nyquist
+	return math.Sqrt(sum / nyquist / 3)
+}
+
+// calibrate computes the per-color gains that bring every color to the
+// same K-weighted loudness.
+func (g *Generator) calibrate() {
+	g.gains = make(map[Color]float64)
+	for _, color := range []Color{White, Pink, Brown, Blue, Violet} {
+		g.gains[color] = targetLoudness / g.loudness(func(f float64) float64 {
+			return g.colorPower(color, f)
+		})
+	}
+}
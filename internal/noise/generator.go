@@ -1,7 +1,10 @@
 package noise
 
 import (
+	"math"
 	"math/rand"
+
+	"github.com/agusx1211/pink-noise/internal/filter"
 )
 
 type Color string
@@ -12,11 +15,15 @@ const (
 	Brown  Color = "brown"
 	Blue   Color = "blue"
 	Violet Color = "violet"
+	// Grey follows an inverse equal-loudness contour, see SetGrey. It sits
+	// outside the color slider and is selected with SoundGrey.
+	Grey Color = "grey"
 )
 
 type Generator struct {
 	sampleRate      int
-	rng             *rand.Rand
+	rng             *rand.Rand // noise samples
+	events          *rand.Rand // timing and shape of waves and shushes
 	white           float64
 	pink            [7]float64
 	brown           float64
@@ -24,25 +31,64 @@ type Generator struct {
 	violetPrevWhite float64
 	violetPrevBlue  float64
 
-	// Second generator state for blending
-	pink2            [7]float64
-	brown2           float64
-	bluePrev2        float64
-	violetPrevWhite2 float64
-	violetPrevBlue2  float64
+	// Color slider position GenerateBlended is currently playing, gliding
+	// towards the requested one
+	blendSlider  float64
+	blendStarted bool
+
+	// Filter coefficients designed for sampleRate, see designFilters
+	pinkCoeffs    [7]float64
+	brownInput    float64
+	brownFeedback float64
+	blueLimit     []*filter.Biquad // nil when no band limit is needed
+	violetLimit   []*filter.Biquad
+	slopeLimit    []*filter.Biquad
+
+	// Per-color gains that equalize loudness, see calibrate
+	gains map[Color]float64
+
+	colorMode ColorMode
+	slope     slopeState
+
+	rain     rainState
+	ocean    oceanState
+	shush    shushState
+	spectrum spectrumState
+	grey     greyState
 }
 
 func NewGenerator(sampleRate int) *Generator {
-	return &Generator{
+	return NewSeededGenerator(sampleRate, rand.Int63(), rand.Int63())
+}
+
+// NewSeededGenerator creates a generator whose noise is drawn from seed and
+// whose events (ocean waves and shushes) are drawn from eventSeed.
+// Generators sharing an eventSeed but not a seed produce matched sounds with
+// independent noise, e.g. the two channels of a stereo pair, as long as they
+// receive the same settings.
+func NewSeededGenerator(sampleRate int, seed, eventSeed int64) *Generator {
+	g := &Generator{
 		sampleRate: sampleRate,
-		rng:        rand.New(rand.NewSource(rand.Int63())),
+		rng:        rand.New(rand.NewSource(seed)),
+		events:     rand.New(rand.NewSource(eventSeed)),
+		colorMode:  ColorBlend,
+		rain:       rainState{density: 50, intensity: 50},
 	}
+	g.designFilters()
+	g.calibrate()
+	g.SetOcean(8, 70, 30)
+	g.SetShush(50, 60, 20)
+	g.SetSpectrum(nil)
+	g.SetGrey(40)
+	return g
 }
 
-// Reseed replaces the internal RNG with a new one seeded from the given value.
+// Reseed replaces the internal RNGs with new ones seeded from the given
+// values, see NewSeededGenerator.
 // Caller must ensure this is not called concurrently with Generate/GenerateBlended.
-func (g *Generator) Reseed(seed int64) {
+func (g *Generator) Reseed(seed, eventSeed int64) {
 	g.rng = rand.New(rand.NewSource(seed))
+	g.events = rand.New(rand.NewSource(eventSeed))
 }
 
 // colorAnchors maps slider position to Color: 0=Brown, 25=Pink, 50=White, 75=Blue, 100=Violet
@@ -57,71 +103,95 @@ var colorAnchors = []struct {
 	{100, Violet},
 }
 
+// colorGlideTime is the time constant in seconds over which GenerateBlended
+// follows the color slider, so moving it never clicks.
+const colorGlideTime = 0.05
+
+// blendPosition returns the colorAnchors segment the slider falls in and the
+// position t (0-1) within it.
+func blendPosition(colorSlider float64) (int, float64) {
+	for i := 0; i < len(colorAnchors)-2; i++ {
+		if colorSlider < colorAnchors[i+1].pos {
+			lo, hi := colorAnchors[i].pos, colorAnchors[i+1].pos
+			return i, math.Max(0, (colorSlider-lo)/(hi-lo))
+		}
+	}
+	n := len(colorAnchors) - 2
+	lo, hi := colorAnchors[n].pos, colorAnchors[n+1].pos
+	return n, math.Min(1, (colorSlider-lo)/(hi-lo))
+}
+
 // GenerateBlended maps a 0-100 color slider to two adjacent noise colors
-// and crossfades between them.
+// and crossfades between them. The crossfade is equal-power: both streams are
+// calibrated to the same loudness and uncorrelated, so every slider position
+// plays at the same loudness. Slider changes are followed gradually, sample
+// by sample, and each color keeps its own filter state, so the sound morphs
+// without zipper noise even across anchor colors.
 func (g *Generator) GenerateBlended(colorSlider float64, samples int, volume float64) []float64 {
-	if colorSlider <= 0 {
-		return g.generateColor(Brown, true, samples, volume)
-	}
-	if colorSlider >= 100 {
-		return g.generateColor(Violet, true, samples, volume)
-	}
-
-	for i := 0; i < len(colorAnchors)-1; i++ {
-		lo := colorAnchors[i]
-		hi := colorAnchors[i+1]
-		if colorSlider >= lo.pos && colorSlider <= hi.pos {
-			t := (colorSlider - lo.pos) / (hi.pos - lo.pos)
-			if t <= 0.001 {
-				return g.generateColor(lo.color, true, samples, volume)
-			}
-			if t >= 0.999 {
-				return g.generateColor(hi.color, true, samples, volume)
-			}
-			samplesA := g.generateColor(lo.color, true, samples, volume)
-			samplesB := g.generateColor(hi.color, false, samples, volume)
-			result := make([]float64, samples)
-			for j := 0; j < samples; j++ {
-				result[j] = samplesA[j]*(1-t) + samplesB[j]*t
-			}
-			return result
+	colorSlider = math.Max(0, math.Min(100, colorSlider))
+	if !g.blendStarted {
+		g.blendSlider = colorSlider
+		g.blendStarted = true
+	}
+
+	// Glide the slider and note which colors are audible in this buffer
+	step := 1 - math.Exp(-1/(colorGlideTime*float64(g.sampleRate)))
+	segments := make([]int, samples)
+	positions := make([]float64, samples)
+	used := make([]bool, len(colorAnchors))
+	for j := range samples {
+		g.blendSlider += (colorSlider - g.blendSlider) * step
+		i, t := blendPosition(g.blendSlider)
+		segments[j], positions[j] = i, t
+		used[i] = used[i] || t < 1
+		used[i+1] = used[i+1] || t > 0
+	}
+	if math.Abs(colorSlider-g.blendSlider) < 1e-3 {
+		g.blendSlider = colorSlider
+	}
+
+	streams := make([][]float64, len(colorAnchors))
+	for i, anchor := range colorAnchors {
+		if used[i] {
+			streams[i] = g.generateColor(anchor.color, samples, volume)
 		}
 	}
 
-	return g.generateColor(White, true, samples, volume)
+	result := make([]float64, samples)
+	for j := range samples {
+		i, t := segments[j], positions[j]
+		if t < 1 {
+			result[j] += streams[i][j] * math.Cos(t*math.Pi/2)
+		}
+		if t > 0 {
+			result[j] += streams[i+1][j] * math.Sin(t*math.Pi/2)
+		}
+	}
+	return result
 }
 
-func (g *Generator) generateColor(color Color, primary bool, samples int, volume float64) []float64 {
+func (g *Generator) generateColor(color Color, samples int, volume float64) []float64 {
+	volume *= g.gains[color]
 	switch color {
 	case White:
 		return g.generateWhite(samples, volume)
 	case Pink:
-		if primary {
-			return g.generatePinkState(&g.pink, samples, volume)
-		}
-		return g.generatePinkState(&g.pink2, samples, volume)
+		return g.generatePinkState(&g.pink, samples, volume)
 	case Brown:
-		if primary {
-			return g.generateBrownState(&g.brown, samples, volume)
-		}
-		return g.generateBrownState(&g.brown2, samples, volume)
+		return g.generateBrownState(&g.brown, samples, volume)
 	case Blue:
-		if primary {
-			return g.generateBlueState(&g.bluePrev, samples, volume)
-		}
-		return g.generateBlueState(&g.bluePrev2, samples, volume)
+		return g.generateBlueState(&g.bluePrev, samples, volume)
 	case Violet:
-		if primary {
-			return g.generateVioletState(&g.violetPrevWhite, &g.violetPrevBlue, samples, volume)
-		}
-		return g.generateVioletState(&g.violetPrevWhite2, &g.violetPrevBlue2, samples, volume)
+		return g.generateVioletState(&g.violetPrevWhite, &g.violetPrevBlue, samples, volume)
+	case Grey:
+		return g.generateGrey(samples, volume)
 	default:
 		return g.generateWhite(samples, volume)
 	}
 }
 
 func (g *Generator) Generate(color Color, samples int, volume float64) []float64 {
-	return g.generateColor(color, true, samples, volume)
+	return g.generateColor(color, samples, volume)
 }
 
 func (g *Generator) generateWhite(samples int, volume float64) []float64 {
@@ -132,9 +202,52 @@ func (g *Generator) generateWhite(samples int, volume float64) []float64 {
 	return result
 }
 
+// pinkCorners are the corner frequencies in Hz of the seven one-pole
+// low-passes summed to approximate pink noise. They were originally tuned as
+// raw coefficients at 44.1 kHz; keeping them in Hz gives the same spectrum at
+// any sample rate.
+var pinkCorners = [7]float64{972.6, 1457.4, 1939.1, 2529.5, 3389.2, 4811.9, 8922.1}
+
+// brownCorner is the corner frequency in Hz of the leaky integrator that
+// turns white noise brown; below it the spectrum flattens out.
+const brownCorner = 139.0
+
+// colorBandLimit is the frequency in Hz above which blue and violet noise,
+// and slope-mode noise, are cut. Their spectra rise all the way to Nyquist, so at high sample rates
+// most of their energy would otherwise sit above hearing, where it only
+// feeds the limiter.
+const colorBandLimit = 20000.0
+
+// bandLimitQs are the Q factors of the two sections of a 4th-order
+// Butterworth low-pass, steep enough to turn even violet noise's +12
+// dB/octave rise into a fall.
+var bandLimitQs = []float64{0.5412, 1.3066}
+
+// designFilters computes the pink and brown filter coefficients for the
+// generator's sample rate, and the blue, violet and slope-mode band limits.
+func (g *Generator) designFilters() {
+	sr := float64(g.sampleRate)
+	for i, f := range pinkCorners {
+		g.pinkCoeffs[i] = 1 - math.Exp(-2*math.Pi*f/sr)
+	}
+	// Unity DC gain: y = (1-a)*x + a*y
+	g.brownFeedback = math.Exp(-2 * math.Pi * brownCorner / sr)
+	g.brownInput = 1 - g.brownFeedback
+
+	// Up to 48 kHz Nyquist itself is the band limit
+	g.blueLimit, g.violetLimit, g.slopeLimit = nil, nil, nil
+	if sr/2 > 24000 {
+		for _, q := range bandLimitQs {
+			g.blueLimit = append(g.blueLimit, filter.New(filter.LowPass, colorBandLimit, q, 0, sr))
+			g.violetLimit = append(g.violetLimit, filter.New(filter.LowPass, colorBandLimit, q, 0, sr))
+			g.slopeLimit = append(g.slopeLimit, filter.New(filter.LowPass, colorBandLimit, q, 0, sr))
+		}
+	}
+}
+
 func (g *Generator) generatePinkState(state *[7]float64, samples int, volume float64) []float64 {
 	result := make([]float64, samples)
-	coeffs := [7]float64{0.1294, 0.1875, 0.2414, 0.3026, 0.3830, 0.4962, 0.7195}
+	coeffs := g.pinkCoeffs
 
 	for i := range samples {
 		white := g.rng.Float64()*2 - 1
@@ -146,7 +259,7 @@ func (g *Generator) generatePinkState(state *[7]float64, samples int, volume flo
 		state[5] = coeffs[5]*(white-state[5]) + state[5]
 		state[6] = coeffs[6]*(white-state[6]) + state[6]
 
-		pink := (state[0] + state[1] + state[2] + state[3] + state[4] + state[5] + state[6]) / 2.5
+		pink := state[0] + state[1] + state[2] + state[3] + state[4] + state[5] + state[6]
 		result[i] = pink * volume
 	}
 	return result
@@ -156,8 +269,8 @@ func (g *Generator) generateBrownState(state *float64, samples int, volume float
 	result := make([]float64, samples)
 	for i := range samples {
 		white := g.rng.Float64()*2 - 1
-		*state = (*state + 0.02*white) / 1.02
-		result[i] = *state * 3.5 * volume
+		*state = g.brownInput*white + g.brownFeedback**state
+		result[i] = *state * volume
 	}
 	return result
 }
@@ -169,6 +282,9 @@ func (g *Generator) generateBlueState(prev *float64, samples int, volume float64
 		result[i] = (white - *prev) * volume
 		*prev = white
 	}
+	for _, b := range g.blueLimit {
+		b.Process(result)
+	}
 	return result
 }
 
@@ -181,6 +297,9 @@ func (g *Generator) generateVioletState(prevWhite, prevBlue *float64, samples in
 		*prevBlue = blue
 		*prevWhite = white
 	}
+	for _, b := range g.violetLimit {
+		b.Process(result)
+	}
 	return result
 }
 
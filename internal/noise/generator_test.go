@@ -0,0 +1,128 @@
+package noise
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/agusx1211/pink-noise/internal/fft"
+)
+
+var testSampleRates = []int{44100, 48000, 96000}
+
+// testColors are the anchor colors with their nominal power spectrum slopes
+// in dB/octave.
+var testColors = []struct {
+	color Color
+	slope float64
+}{
+	{Brown, -6},
+	{Pink, -3},
+	{White, 0},
+	{Blue, 6},
+	{Violet, 12},
+}
+
+// octaveLevels returns the average power spectral density in dB of x in
+// octave bands centered on 125 Hz to 8 kHz, from a Welch periodogram.
+func octaveLevels(x []float64, sampleRate int) []float64 {
+	const n = 8192
+	psd := make([]float64, n/2)
+	buf := make([]complex128, n)
+	for off := 0; off+n <= len(x); off += n / 2 {
+		for i := range n {
+			window := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/n)
+			buf[i] = complex(x[off+i]*window, 0)
+		}
+		fft.Forward(buf)
+		for i := range psd {
+			a := cmplx.Abs(buf[i])
+			psd[i] += a * a
+		}
+	}
+
+	var levels []float64
+	for center := 125.0; center <= 8000; center *= 2 {
+		var sum float64
+		var count int
+		for i, p := range psd {
+			f := float64(i) * float64(sampleRate) / n
+			if f >= center/math.Sqrt2 && f < center*math.Sqrt2 {
+				sum += p
+				count++
+			}
+		}
+		levels = append(levels, 10*math.Log10(sum/float64(count)))
+	}
+	return levels
+}
+
+func colorLevels(color Color, sampleRate int) []float64 {
+	g := NewSeededGenerator(sampleRate, 1, 2)
+	return octaveLevels(g.Generate(color, 4*sampleRate, 1), sampleRate)
+}
+
+func TestColorSlopes(t *testing.T) {
+	for _, tc := range testColors {
+		for _, sr := range testSampleRates {
+			levels := colorLevels(tc.color, sr)
+			// Octaves 2, 4 and 8 kHz are the last three bands
+			n := len(levels)
+			slope := (levels[n-1] - levels[n-3]) / 2
+			if math.Abs(slope-tc.slope) > 1 {
+				t.Errorf("%s at %d Hz: slope %.2f dB/octave, want %.0f", tc.color, sr, slope, tc.slope)
+			}
+		}
+	}
+}
+
+// TestColorSpectrumMatchesAcrossRates checks every color has the shape it
+// has at 44.1 kHz, octave by octave, at the other rates.
+func TestColorSpectrumMatchesAcrossRates(t *testing.T) {
+	for _, tc := range testColors {
+		ref := colorLevels(tc.color, testSampleRates[0])
+		for _, sr := range testSampleRates[1:] {
+			levels := colorLevels(tc.color, sr)
+			for i := 1; i < len(levels); i++ {
+				want := ref[i] - ref[i-1]
+				got := levels[i] - levels[i-1]
+				if math.Abs(got-want) > 1 {
+					t.Errorf("%s at %d Hz: octave %d rises %.2f dB, %.2f dB at 44.1 kHz", tc.color, sr, i, got, want)
+				}
+			}
+		}
+	}
+}
+
+// TestColorLevelAcrossRates checks blue and violet, in both color modes,
+// don't pile energy up above hearing at high sample rates, where it would be
+// calibrated out of the audible band and drive the limiter.
+func TestColorLevelAcrossRates(t *testing.T) {
+	rms := func(x []float64) float64 {
+		var sum float64
+		for _, v := range x {
+			sum += v * v
+		}
+		return math.Sqrt(sum / float64(len(x)))
+	}
+	for _, color := range []Color{Blue, Violet} {
+		ref := rms(NewSeededGenerator(44100, 1, 2).Generate(color, 44100, 1))
+		for _, sr := range testSampleRates[1:] {
+			got := rms(NewSeededGenerator(sr, 1, 2).Generate(color, sr, 1))
+			if got > ref*1.25 || got < ref/1.25 {
+				t.Errorf("%s at %d Hz: rms %.3f, %.3f at 44.1 kHz", color, sr, got, ref)
+			}
+		}
+	}
+	for _, slider := range []float64{75, 100} {
+		slope := func(sr int) float64 {
+			return rms(NewSeededGenerator(sr, 1, 2).GenerateSlope(slider, sr, 1))
+		}
+		ref := slope(44100)
+		for _, sr := range testSampleRates[1:] {
+			if got := slope(sr); got > ref*1.25 || got < ref/1.25 {
+				t.Errorf("slope mode at slider %.0f, %d Hz: rms %.3f, %.3f at 44.1 kHz", slider, sr, got, ref)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package noise
+
+import "math"
+
+// ISO 226:2003 equal-loudness contour parameters: frequency, exponent of
+// loudness perception, magnitude of the linear transfer function and
+// threshold of hearing.
+var (
+	iso226Freq = []float64{20, 25, 31.5, 40, 50, 63, 80, 100, 125, 160, 200, 250, 315, 400, 500, 630, 800, 1000,
+		1250, 1600, 2000, 2500, 3150, 4000, 5000, 6300, 8000, 10000, 12500}
+	iso226Af = []float64{0.532, 0.506, 0.480, 0.455, 0.432, 0.409, 0.387, 0.367, 0.349, 0.330, 0.315, 0.301, 0.288, 0.276,
+		0.267, 0.259, 0.253, 0.250, 0.246, 0.244, 0.243, 0.243, 0.243, 0.242, 0.242, 0.245, 0.254, 0.271, 0.301}
+	iso226Lu = []float64{-31.6, -27.2, -23.0, -19.1, -15.9, -13.0, -10.3, -8.1, -6.2, -4.5, -3.1, -2.0, -1.1, -0.4,
+		0.0, 0.3, 0.5, 0.0, -2.7, -4.1, -1.0, 1.7, 2.5, 1.2, -2.1, -7.1, -11.2, -10.7, -3.1}
+	iso226Tf = []float64{78.5, 68.7, 59.5, 51.1, 44.0, 37.5, 31.5, 26.5, 22.1, 17.9, 14.4, 11.4, 8.6, 6.2,
+		4.4, 3.0, 2.2, 2.4, 3.5, 1.7, -1.3, -4.2, -6.0, -5.4, -1.5, 6.0, 12.6, 13.9, 12.3}
+)
+
+// greyMaxBoost caps how far grey noise lifts any frequency above 1 kHz, in
+// dB. The contours climb steeply towards 20 Hz, and rumble beyond this is
+// mostly felt rather than heard.
+const greyMaxBoost = 24.0
+
+type greyState struct {
+	phon   float64
+	points []SpectrumPoint // contour relative to 1 kHz, in dB
+	shaper shaper
+}
+
+// equalLoudness returns the sound pressure level in dB SPL at each ISO 226
+// frequency that sounds as loud as a 1 kHz tone at the given phon level.
+func equalLoudness(phon float64) []float64 {
+	levels := make([]float64, len(iso226Freq))
+	for i := range iso226Freq {
+		af := 4.47e-3*(math.Pow(10, 0.025*phon)-1.15) +
+			math.Pow(0.4*math.Pow(10, (iso226Tf[i]+iso226Lu[i])/10-9), iso226Af[i])
+		levels[i] = 10/iso226Af[i]*math.Log10(af) - iso226Lu[i] + 94
+	}
+	return levels
+}
+
+// SetGrey sets the loudness level in phon (20-80) whose equal-loudness
+// contour grey noise follows. Quieter listening levels call for more bass.
+func (g *Generator) SetGrey(phon float64) {
+	s := &g.grey
+	s.phon = math.Max(20, math.Min(80, phon))
+
+	levels := equalLoudness(s.phon)
+	ref := levels[17] // 1 kHz
+	s.points = s.points[:0]
+	for i, f := range iso226Freq {
+		s.points = append(s.points, SpectrumPoint{Freq: f, DB: math.Min(greyMaxBoost, levels[i]-ref)})
+	}
+
+	s.shaper.design(g.sampleRate, g.greyPower)
+	g.gains[Grey] = targetLoudness / g.loudness(g.greyPower)
+}
+
+// greyPower returns the power response of grey noise at f.
+func (g *Generator) greyPower(f float64) float64 {
+	return curvePower(g.grey.points, f)
+}
+
+// generateGrey shapes white noise by the inverse equal-loudness contour, so
+// every frequency sounds equally loud.
+func (g *Generator) generateGrey(samples int, volume float64) []float64 {
+	return g.grey.shaper.generate(g.rng, samples, volume)
+}
@@ -0,0 +1,78 @@
+package noise
+
+import "math"
+
+// Heartbeat synthesizes a muffled maternal heartbeat: a "lub" thump followed
+// by a softer, slightly higher "dub", repeating at the configured rate.
+type Heartbeat struct {
+	sampleRate int
+	bpm        float64
+
+	t     float64 // seconds since the current beat started
+	phase [2]float64
+}
+
+func NewHeartbeat(sampleRate int) *Heartbeat {
+	return &Heartbeat{
+		sampleRate: sampleRate,
+		bpm:        70,
+	}
+}
+
+func (h *Heartbeat) SetBPM(bpm float64) {
+	h.bpm = math.Max(40, math.Min(140, bpm))
+}
+
+// thump describes one of the two heart sounds within a beat.
+var thumps = [2]struct {
+	onset  float64 // seconds after the beat starts
+	freq   float64 // Hz
+	amp    float64
+	attack float64 // seconds
+	decay  float64 // seconds
+}{
+	{0, 50, 1.0, 0.012, 0.07},
+	{0.28, 65, 0.6, 0.010, 0.05},
+}
+
+func (h *Heartbeat) Generate(samples int, volume float64) []float64 {
+	result := make([]float64, samples)
+	dt := 1 / float64(h.sampleRate)
+	beat := 60 / h.bpm
+
+	for i := range samples {
+		var out float64
+		for k, th := range thumps {
+			// Keep the dub inside the beat at high rates
+			onset := math.Min(th.onset, beat*0.4)
+			t := h.t - onset
+			if t < 0 {
+				continue
+			}
+
+			var env float64
+			if t < th.attack {
+				env = t / th.attack
+			} else {
+				env = math.Exp(-(t - th.attack) / th.decay)
+			}
+			if env < 1e-4 {
+				continue
+			}
+
+			// Pitch drops as the thump decays; the second harmonic keeps it
+			// audible on small speakers that can't reproduce 50 Hz
+			freq := th.freq * (1 + 0.5*env)
+			h.phase[k] += 2 * math.Pi * freq * dt
+			out += th.amp * env * (0.75*math.Sin(h.phase[k]) + 0.25*math.Sin(2*h.phase[k]))
+		}
+
+		h.t += dt
+		if h.t >= beat {
+			h.t -= beat
+		}
+
+		result[i] = out * volume
+	}
+	return result
+}
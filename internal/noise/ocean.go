@@ -0,0 +1,69 @@
+package noise
+
+import "math"
+
+type oceanState struct {
+	period     float64 // seconds per wave
+	depth      float64 // 0-100
+	randomness float64 // 0-100
+
+	brown   float64
+	pink    [7]float64
+	lowpass float64
+
+	// Swell LFO: phase runs 0-1 over one wave, with the period and crest
+	// height re-rolled at the start of every wave
+	phase      float64
+	wavePeriod float64
+	wavePeak   float64
+}
+
+// SetOcean sets the average wave period in seconds, the swell depth (0-100)
+// and how much each wave's period and height vary (0-100).
+func (g *Generator) SetOcean(period, depth, randomness float64) {
+	g.ocean.period = math.Max(2, math.Min(30, period))
+	g.ocean.depth = math.Max(0, math.Min(100, depth))
+	g.ocean.randomness = math.Max(0, math.Min(100, randomness))
+	if g.ocean.wavePeriod == 0 {
+		g.nextWave()
+	}
+}
+
+func (g *Generator) nextWave() {
+	o := &g.ocean
+	spread := o.randomness / 100
+	o.wavePeriod = o.period * (1 + spread*(g.events.Float64()-0.5))
+	o.wavePeak = 1 - spread*0.5*g.events.Float64()
+}
+
+// generateOcean mixes brown and pink noise and sweeps both the amplitude and
+// a low-pass cutoff with a slow swell, so the sound rolls in and out.
+func (g *Generator) generateOcean(samples int, volume float64) []float64 {
+	o := &g.ocean
+	sr := float64(g.sampleRate)
+	depth := o.depth / 100
+
+	brown := g.generateBrownState(&o.brown, samples, g.gains[Brown])
+	pink := g.generatePinkState(&o.pink, samples, g.gains[Pink])
+
+	result := make([]float64, samples)
+	for i := range samples {
+		o.phase += 1 / (o.wavePeriod * sr)
+		if o.phase >= 1 {
+			o.phase -= 1
+			g.nextWave()
+		}
+
+		// Raised cosine sharpened into a crest
+		swell := math.Pow((1-math.Cos(2*math.Pi*o.phase))/2, 1.5) * o.wavePeak
+
+		// Cutoff sweeps 200 Hz in the trough to 3 kHz at the crest
+		cutoff := 200 * math.Pow(15, swell)
+		coeff := 1 - math.Exp(-2*math.Pi*cutoff/sr)
+		o.lowpass += coeff * (0.6*brown[i] + 0.4*pink[i] - o.lowpass)
+
+		amp := 1 - depth*(1-swell)
+		result[i] = o.lowpass * amp * 2 * volume
+	}
+	return result
+}
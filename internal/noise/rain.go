@@ -0,0 +1,87 @@
+package noise
+
+import "math"
+
+const maxDrops = 64
+
+type drop struct {
+	amp   float64
+	phase float64
+	freq  float64 // Hz, rises slightly as the bubble collapses
+	chirp float64 // per-sample frequency multiplier
+	decay float64 // per-sample amplitude multiplier
+}
+
+type rainState struct {
+	density   float64 // 0-100
+	intensity float64 // 0-100
+
+	pink     [7]float64
+	highpass float64
+	prevBed  float64
+	drops    []drop
+}
+
+// SetRain sets rain density (how many droplets) and intensity (how loud
+// they and the background wash are), both 0-100.
+func (g *Generator) SetRain(density, intensity float64) {
+	g.rain.density = math.Max(0, math.Min(100, density))
+	g.rain.intensity = math.Max(0, math.Min(100, intensity))
+}
+
+// generateRain synthesizes rain as a high-passed pink noise bed plus
+// randomly timed, randomly pitched droplet transients.
+func (g *Generator) generateRain(samples int, volume float64) []float64 {
+	r := &g.rain
+	sr := float64(g.sampleRate)
+	intensity := r.intensity / 100
+
+	bed := g.generatePinkState(&r.pink, samples, g.gains[Pink])
+
+	// One-pole high-pass at ~300 Hz keeps the bed from sounding like surf
+	hpCoeff := math.Exp(-2 * math.Pi * 300 / sr)
+	bedLevel := 0.3 + 0.7*intensity
+
+	// Up to 400 drops per second at full density
+	dropChance := r.density / 100 * 400 / sr
+
+	result := make([]float64, samples)
+	for i := range samples {
+		r.highpass = hpCoeff * (r.highpass + bed[i] - r.prevBed)
+		r.prevBed = bed[i]
+		out := r.highpass * bedLevel
+
+		if len(r.drops) < maxDrops && g.rng.Float64() < dropChance {
+			r.drops = append(r.drops, g.newDrop(sr, intensity))
+		}
+
+		live := r.drops[:0]
+		for _, d := range r.drops {
+			out += d.amp * math.Sin(d.phase)
+			d.phase += 2 * math.Pi * d.freq / sr
+			d.freq *= d.chirp
+			d.amp *= d.decay
+			if d.amp > 1e-4 {
+				live = append(live, d)
+			}
+		}
+		r.drops = live
+
+		result[i] = out * volume
+	}
+	return result
+}
+
+func (g *Generator) newDrop(sr, intensity float64) drop {
+	// Log-uniform pitch between 1 and 6 kHz, 2-10 ms decay
+	freq := 1000 * math.Pow(6, g.rng.Float64())
+	decayTime := 0.002 + 0.008*g.rng.Float64()
+	// Squared random amplitude: mostly small drops, a few big ones
+	a := g.rng.Float64()
+	return drop{
+		amp:   a * a * (0.2 + 0.6*intensity),
+		freq:  freq,
+		chirp: math.Pow(1.5, 1/(decayTime*sr)),
+		decay: math.Exp(-1 / (decayTime * sr)),
+	}
+}
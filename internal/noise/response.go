@@ -0,0 +1,90 @@
+package noise
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// SoundPower returns the long-term average power response of a sound at f,
+// including its loudness calibration, relative to uniform white noise in
+// [-1, 1]. For colored noise it is exact; for the procedural sounds it
+// describes their steady noise bed and ignores rain drops and the motion of
+// waves and shushes.
+func (g *Generator) SoundPower(sound Sound, colorSlider, f float64) float64 {
+	switch sound {
+	case SoundRain:
+		return g.rainPower(f)
+	case SoundOcean:
+		return g.oceanPower(f)
+	case SoundShush:
+		return g.shushPower(f)
+	case SoundGrey:
+		return g.gains[Grey] * g.gains[Grey] * g.greyPower(f)
+	case SoundSpectrum:
+		return g.spectrum.gain * g.spectrum.gain * g.spectrumPower(f)
+	default:
+		if g.colorMode == ColorSlope {
+			// Read-only, so the response can be asked for while playing
+			sections, gain := g.slope.sections, g.slope.targetGain
+			if !g.slope.designed || g.slope.slider != colorSlider {
+				sections, gain = g.slopeDesign(colorSlider)
+			}
+			return gain * gain * g.slopePower(sections, f)
+		}
+		return g.blendedPower(colorSlider, f)
+	}
+}
+
+// calibratedPower returns the power response of a color at its calibrated
+// gain.
+func (g *Generator) calibratedPower(color Color, f float64) float64 {
+	return g.gains[color] * g.gains[color] * g.colorPower(color, f)
+}
+
+// blendedPower is the power response of GenerateBlended. The two streams are
+// uncorrelated, so their powers add.
+func (g *Generator) blendedPower(colorSlider, f float64) float64 {
+	i, t := blendPosition(math.Max(0, math.Min(100, colorSlider)))
+	lo := math.Cos(t * math.Pi / 2)
+	hi := math.Sin(t * math.Pi / 2)
+	return lo*lo*g.calibratedPower(colorAnchors[i].color, f) +
+		hi*hi*g.calibratedPower(colorAnchors[i+1].color, f)
+}
+
+// onePole returns the power response of y = c*x + (1-c)*y, or of its
+// complementary high-pass.
+func (g *Generator) onePole(c, f float64, highpass bool) float64 {
+	zInv := cmplx.Exp(complex(0, -2*math.Pi*f/float64(g.sampleRate)))
+	h := complex(c, 0) / (1 - complex(1-c, 0)*zInv)
+	if highpass {
+		h = 1 - h
+	}
+	return real(h)*real(h) + imag(h)*imag(h)
+}
+
+// rainPower is the power response of the rain bed: high-passed pink noise.
+func (g *Generator) rainPower(f float64) float64 {
+	sr := float64(g.sampleRate)
+	bedLevel := 0.3 + 0.7*g.rain.intensity/100
+	hp := g.onePole(1-math.Exp(-2*math.Pi*300/sr), f, true)
+	return bedLevel * bedLevel * hp * g.calibratedPower(Pink, f)
+}
+
+// oceanPower is the power response of the ocean at the middle of its swell,
+// with the low-pass cutoff at its geometric mean.
+func (g *Generator) oceanPower(f float64) float64 {
+	sr := float64(g.sampleRate)
+	depth := g.ocean.depth / 100
+	amp := 1 - depth/2
+	bed := 0.36*g.calibratedPower(Brown, f) + 0.16*g.calibratedPower(Pink, f)
+	lp := g.onePole(1-math.Exp(-2*math.Pi*200*math.Sqrt(15)/sr), f, false)
+	return 4 * amp * amp * lp * bed
+}
+
+// shushPower is the power response of a shush at its peak.
+func (g *Generator) shushPower(f float64) float64 {
+	s := &g.shush
+	zInv := cmplx.Exp(complex(0, -2*math.Pi*f/float64(g.sampleRate)))
+	h := complex(s.b0, 0) * (1 - zInv*zInv) / (1 + complex(s.a1, 0)*zInv + complex(s.a2, 0)*zInv*zInv)
+	return 1.1 * 1.1 * (real(h)*real(h) + imag(h)*imag(h))
+}
@@ -0,0 +1,90 @@
+package noise
+
+import "math"
+
+type shushState struct {
+	rate       float64 // shushes per minute
+	duty       float64 // 10-90, percent of each cycle spent shushing
+	randomness float64 // 0-100
+
+	// Band-pass biquad around the "sh" formant
+	b0, b2, a1, a2 float64
+	x1, x2, y1, y2 float64
+
+	// Current cycle, re-rolled at the start of every shush
+	phase       float64
+	cyclePeriod float64
+	cycleDuty   float64
+	cycleAmp    float64
+}
+
+// SetShush sets the shush rate in shushes per minute, the duty cycle
+// (percent of each cycle spent shushing) and how much each shush varies.
+func (g *Generator) SetShush(rate, duty, randomness float64) {
+	g.shush.rate = math.Max(10, math.Min(120, rate))
+	g.shush.duty = math.Max(10, math.Min(90, duty))
+	g.shush.randomness = math.Max(0, math.Min(100, randomness))
+	if g.shush.cyclePeriod == 0 {
+		g.designShushFilter()
+		g.nextShush()
+	}
+}
+
+// designShushFilter computes an RBJ constant-peak band-pass centered on the
+// ~3 kHz "sh" formant.
+func (g *Generator) designShushFilter() {
+	s := &g.shush
+	w0 := 2 * math.Pi * 3000 / float64(g.sampleRate)
+	alpha := math.Sin(w0) / (2 * 0.9)
+	a0 := 1 + alpha
+	s.b0 = alpha / a0
+	s.b2 = -alpha / a0
+	s.a1 = -2 * math.Cos(w0) / a0
+	s.a2 = (1 - alpha) / a0
+}
+
+func (g *Generator) nextShush() {
+	s := &g.shush
+	spread := s.randomness / 100
+	s.cyclePeriod = 60 / s.rate * (1 + spread*0.5*(g.events.Float64()-0.5))
+	s.cycleDuty = math.Max(0.1, math.Min(0.9, s.duty/100*(1+spread*0.4*(g.events.Float64()-0.5))))
+	s.cycleAmp = 1 - spread*0.4*g.events.Float64()
+}
+
+// shushFloor is the level between shushes, so the gaps aren't dead silent.
+const shushFloor = 0.08
+
+// generateShush band-passes white noise around the "sh" formant and gates
+// it with a soft rhythmic envelope, like a parent shushing.
+func (g *Generator) generateShush(samples int, volume float64) []float64 {
+	s := &g.shush
+	sr := float64(g.sampleRate)
+
+	result := make([]float64, samples)
+	for i := range samples {
+		s.phase += 1 / (s.cyclePeriod * sr)
+		if s.phase >= 1 {
+			s.phase -= 1
+			g.nextShush()
+		}
+
+		// Raised-cosine envelope over the on part of the cycle, with the
+		// attack and release each taking a fifth of it
+		env := shushFloor
+		if s.phase < s.cycleDuty {
+			pos := s.phase / s.cycleDuty
+			ramp := math.Min(1, math.Min(pos, 1-pos)/0.2)
+			env += (1 - shushFloor) * s.cycleAmp * (1 - math.Cos(math.Pi*ramp)) / 2
+		}
+
+		x := g.rng.Float64()*2 - 1
+		y := s.b0*x + s.b2*s.x2 - s.a1*s.y1 - s.a2*s.y2
+		s.x2 = s.x1
+		s.x1 = x
+		s.y2 = s.y1
+		s.y1 = y
+
+		result[i] = y * env * 1.1 * volume
+	}
+	return result
+}
@@ -0,0 +1,207 @@
+package noise
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/agusx1211/pink-noise/internal/filter"
+)
+
+// ColorMode selects how the color slider is rendered for SoundNoise.
+type ColorMode string
+
+const (
+	// ColorBlend crossfades between the two nearest anchor colors.
+	ColorBlend ColorMode = "blend"
+	// ColorSlope synthesizes noise whose power spectrum is a straight
+	// f^α line, with α running from -2 (brown) to +2 (violet).
+	ColorSlope ColorMode = "slope"
+)
+
+// ColorModes lists every selectable color mode.
+var ColorModes = []ColorMode{ColorBlend, ColorSlope}
+
+// ParseColorMode returns the ColorMode with the given name, defaulting to ColorBlend.
+func ParseColorMode(name string) ColorMode {
+	for _, m := range ColorModes {
+		if string(m) == name {
+			return m
+		}
+	}
+	return ColorBlend
+}
+
+// The slope filter approximates f^α between slopeLow and slopeHigh and is
+// flat outside that band. Above 48 kHz sample rates the output is also cut
+// above colorBandLimit, like blue and violet noise.
+const (
+	slopeLow  = 20.0
+	slopeHigh = 20000.0
+)
+
+// slopeSection is a first-order pole/zero section, matched-z-transformed
+// from H(s) = (s + ωz) / (s + ωp). The coefficients glide towards the target
+// ones from the latest design.
+type slopeSection struct {
+	b0, b1, a1    float64
+	tb0, tb1, ta1 float64
+	x1, y1        float64
+}
+
+type slopeState struct {
+	slider     float64
+	designed   bool
+	ramping    bool
+	sections   []slopeSection
+	gain       float64
+	targetGain float64
+}
+
+// SliderToExponent maps the 0-100 color slider to a spectral exponent α,
+// so 0=Brown (-2), 25=Pink (-1), 50=White (0), 75=Blue (+1), 100=Violet (+2).
+func SliderToExponent(colorSlider float64) float64 {
+	return (colorSlider - 50) / 25
+}
+
+// SetColorMode selects how SoundNoise renders the color slider.
+func (g *Generator) SetColorMode(mode ColorMode) {
+	g.colorMode = mode
+}
+
+// designSlope makes the slope filter glide to the design for the slider.
+// The first design takes effect at once; GenerateSlope glides to later ones.
+func (g *Generator) designSlope(colorSlider float64) {
+	s := &g.slope
+	sections, gain := g.slopeDesign(colorSlider)
+	if len(s.sections) != len(sections) {
+		s.sections = make([]slopeSection, len(sections))
+	}
+	for i, sec := range sections {
+		s.sections[i].tb0, s.sections[i].tb1, s.sections[i].ta1 = sec.tb0, sec.tb1, sec.ta1
+	}
+	s.targetGain = gain
+
+	if !s.designed {
+		for i := range s.sections {
+			sec := &s.sections[i]
+			sec.b0, sec.b1, sec.a1 = sec.tb0, sec.tb1, sec.ta1
+		}
+		s.gain = s.targetGain
+	}
+	s.slider = colorSlider
+	s.designed = true
+	s.ramping = true
+}
+
+// slopeDesign returns the sections, with their target coefficients set, and
+// the calibrated gain of the slope filter for the slider, without touching
+// the filter that is playing. It places one pole/zero pair per octave.
+// Within each octave the response falls (or rises) at 6 dB/octave for a
+// fraction |α|/2 of it, which averages out to the 3α dB/octave of an f^α
+// power spectrum.
+func (g *Generator) slopeDesign(colorSlider float64) ([]slopeSection, float64) {
+	alpha := SliderToExponent(colorSlider)
+	beta := math.Abs(alpha) / 2
+	sr := float64(g.sampleRate)
+	top := math.Min(slopeHigh, sr*0.49)
+
+	// Matched-z sections: the bilinear transform would squeeze the top
+	// octave's rise or fall into the last few kHz below Nyquist, tilting the
+	// spectrum differently at each sample rate. Each section's DC gain is
+	// scaled to its analog one.
+	var sections []slopeSection
+	for f := slopeLow; f < top; f *= 2 {
+		p, z := 2*math.Pi*f, 2*math.Pi*f*math.Pow(2, beta)
+		if alpha > 0 {
+			p, z = z, p
+		}
+		ep, ez := math.Exp(-p/sr), math.Exp(-z/sr)
+		dc := (z / p) * (1 - ep) / (1 - ez)
+		sections = append(sections, slopeSection{
+			tb0: dc,
+			tb1: -dc * ez,
+			ta1: -ep,
+		})
+	}
+	gain := targetLoudness / g.loudness(func(f float64) float64 {
+		return g.slopePower(sections, f)
+	})
+	return sections, gain
+}
+
+// slopePower returns the power response of the target coefficients of the
+// sections, followed by the band limit.
+func (g *Generator) slopePower(sections []slopeSection, f float64) float64 {
+	zInv := cmplx.Exp(complex(0, -2*math.Pi*f/float64(g.sampleRate)))
+	h := complex(1, 0)
+	for _, sec := range sections {
+		h *= (complex(sec.tb0, 0) + complex(sec.tb1, 0)*zInv) / (1 + complex(sec.ta1, 0)*zInv)
+	}
+	limit := filter.CascadeResponse(g.slopeLimit, f)
+	return (real(h)*real(h) + imag(h)*imag(h)) * limit * limit
+}
+
+// GenerateSlope produces noise with a straight f^α power spectrum, where α
+// is derived from the 0-100 color slider. Slider changes are followed
+// gradually, like GenerateBlended's, by gliding the filter coefficients and
+// gain over colorGlideTime, so moving the slider doesn't thump.
+func (g *Generator) GenerateSlope(colorSlider float64, samples int, volume float64) []float64 {
+	s := &g.slope
+	if !s.designed || s.slider != colorSlider {
+		g.designSlope(colorSlider)
+	}
+	step := 1 - math.Exp(-1/(colorGlideTime*float64(g.sampleRate)))
+
+	result := make([]float64, samples)
+	for i := range samples {
+		if s.ramping {
+			s.glide(step)
+		}
+		x := g.rng.Float64()*2 - 1
+		for j := range s.sections {
+			sec := &s.sections[j]
+			y := sec.b0*x + sec.b1*sec.x1 - sec.a1*sec.y1
+			sec.x1 = x
+			sec.y1 = y
+			x = y
+		}
+		result[i] = x * s.gain * volume
+	}
+	if s.ramping {
+		s.settle()
+	}
+	for _, b := range g.slopeLimit {
+		b.Process(result)
+	}
+	return result
+}
+
+// glide moves the coefficients and gain one step towards their targets.
+// The gain glides in proportion, like a level in dB.
+func (s *slopeState) glide(step float64) {
+	for j := range s.sections {
+		sec := &s.sections[j]
+		sec.b0 += (sec.tb0 - sec.b0) * step
+		sec.b1 += (sec.tb1 - sec.b1) * step
+		sec.a1 += (sec.ta1 - sec.a1) * step
+	}
+	s.gain *= math.Pow(s.targetGain/s.gain, step)
+}
+
+// settle snaps to the targets once the glide has all but reached them.
+func (s *slopeState) settle() {
+	for _, sec := range s.sections {
+		if math.Abs(sec.tb0-sec.b0)+math.Abs(sec.tb1-sec.b1)+math.Abs(sec.ta1-sec.a1) > 1e-9 {
+			return
+		}
+	}
+	if math.Abs(s.targetGain/s.gain-1) > 1e-6 {
+		return
+	}
+	for j := range s.sections {
+		sec := &s.sections[j]
+		sec.b0, sec.b1, sec.a1 = sec.tb0, sec.tb1, sec.ta1
+	}
+	s.gain = s.targetGain
+	s.ramping = false
+}
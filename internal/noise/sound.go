@@ -0,0 +1,51 @@
+package noise
+
+// Sound selects what a Generator produces: colored noise driven by the
+// color slider, or one of the procedural soundscapes.
+type Sound string
+
+const (
+	SoundNoise Sound = "noise"
+	SoundRain  Sound = "rain"
+	SoundOcean Sound = "ocean"
+	SoundShush Sound = "shush"
+	// SoundGrey is grey noise, perceptually flat at the configured phon level.
+	SoundGrey Sound = "grey"
+	// SoundSpectrum is white noise shaped to a user-defined spectrum curve.
+	SoundSpectrum Sound = "spectrum"
+)
+
+// Sounds lists every selectable sound type.
+var Sounds = []Sound{SoundNoise, SoundRain, SoundOcean, SoundShush, SoundGrey, SoundSpectrum}
+
+// ParseSound returns the Sound with the given name, defaulting to SoundNoise.
+func ParseSound(name string) Sound {
+	for _, s := range Sounds {
+		if string(s) == name {
+			return s
+		}
+	}
+	return SoundNoise
+}
+
+// GenerateSound produces samples for the given sound type. The color slider
+// only applies to SoundNoise, rendered according to the color mode.
+func (g *Generator) GenerateSound(sound Sound, colorSlider float64, samples int, volume float64) []float64 {
+	switch sound {
+	case SoundRain:
+		return g.generateRain(samples, volume)
+	case SoundOcean:
+		return g.generateOcean(samples, volume)
+	case SoundShush:
+		return g.generateShush(samples, volume)
+	case SoundGrey:
+		return g.generateColor(Grey, samples, volume)
+	case SoundSpectrum:
+		return g.generateSpectrum(samples, volume)
+	default:
+		if g.colorMode == ColorSlope {
+			return g.GenerateSlope(colorSlider, samples, volume)
+		}
+		return g.GenerateBlended(colorSlider, samples, volume)
+	}
+}
@@ -0,0 +1,170 @@
+package noise
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/agusx1211/pink-noise/internal/fft"
+)
+
+// SpectrumPoint is one point of a user-defined spectrum curve: a level in dB
+// at a frequency in Hz.
+type SpectrumPoint struct {
+	Freq float64 `json:"freq"`
+	DB   float64 `json:"db"`
+}
+
+// spectrumSize is the FFT frame length used for spectral shaping. Frames
+// overlap by half, so the shaping latency is spectrumSize samples.
+const spectrumSize = 4096
+
+// Curve levels are clamped to this range so a typo can't blow up the output.
+const (
+	spectrumMinDB = -60.0
+	spectrumMaxDB = 24.0
+)
+
+type spectrumState struct {
+	points []SpectrumPoint
+	gain   float64
+	shaper shaper
+}
+
+// shaper filters white noise to an arbitrary power response by overlap-add
+// in the frequency domain.
+type shaper struct {
+	bins []float64 // amplitude per FFT bin, 0..spectrumSize/2
+
+	window  []float64 // sine window, applied on analysis and synthesis
+	input   []float64 // last spectrumSize white noise samples
+	overlap []float64 // second half of the previous frame
+	frame   []complex128
+	ready   []float64 // shaped samples not yet returned
+}
+
+// SetSpectrum sets the curve used by SoundSpectrum. Points are sorted by
+// frequency; between them the level is interpolated linearly in dB over log
+// frequency, and beyond the ends the nearest level is held. An empty curve
+// plays white noise.
+func (g *Generator) SetSpectrum(points []SpectrumPoint) {
+	var clean []SpectrumPoint
+	for _, p := range points {
+		if p.Freq <= 0 {
+			continue
+		}
+		p.DB = math.Max(spectrumMinDB, math.Min(spectrumMaxDB, p.DB))
+		clean = append(clean, p)
+	}
+	sort.Slice(clean, func(i, j int) bool {
+		return clean[i].Freq < clean[j].Freq
+	})
+	g.spectrum.points = clean
+	g.designSpectrum()
+}
+
+// Spectrum returns a copy of the current spectrum curve.
+func (g *Generator) Spectrum() []SpectrumPoint {
+	return append([]SpectrumPoint(nil), g.spectrum.points...)
+}
+
+// spectrumPower returns the power response of the spectrum curve at f.
+func (g *Generator) spectrumPower(f float64) float64 {
+	return curvePower(g.spectrum.points, f)
+}
+
+// curvePower returns the power of a curve sorted by frequency at f,
+// interpolating linearly in dB over log frequency and holding the level
+// beyond its ends. An empty curve is flat at 0 dB.
+func curvePower(points []SpectrumPoint, f float64) float64 {
+	if len(points) == 0 {
+		return 1
+	}
+
+	db := points[0].DB
+	if f >= points[len(points)-1].Freq {
+		db = points[len(points)-1].DB
+	} else if f > points[0].Freq {
+		i := sort.Search(len(points), func(i int) bool { return points[i].Freq > f })
+		lo, hi := points[i-1], points[i]
+		t := math.Log(f/lo.Freq) / math.Log(hi.Freq/lo.Freq)
+		db = lo.DB + t*(hi.DB-lo.DB)
+	}
+	return math.Pow(10, db/10)
+}
+
+// designSpectrum samples the curve at every FFT bin and calibrates the gain
+// to the shared loudness target.
+func (g *Generator) designSpectrum() {
+	g.spectrum.shaper.design(g.sampleRate, g.spectrumPower)
+	g.spectrum.gain = targetLoudness / g.loudness(g.spectrumPower)
+}
+
+// generateSpectrum produces noise shaped to the user-defined spectrum curve.
+func (g *Generator) generateSpectrum(samples int, volume float64) []float64 {
+	return g.spectrum.shaper.generate(g.rng, samples, g.spectrum.gain*volume)
+}
+
+// design samples the power response at every FFT bin.
+func (s *shaper) design(sampleRate int, power func(f float64) float64) {
+	if s.window == nil {
+		s.window = make([]float64, spectrumSize)
+		for i := range s.window {
+			s.window[i] = math.Sin(math.Pi * (float64(i) + 0.5) / spectrumSize)
+		}
+		s.input = make([]float64, spectrumSize)
+		s.overlap = make([]float64, spectrumSize/2)
+		s.frame = make([]complex128, spectrumSize)
+		s.bins = make([]float64, spectrumSize/2+1)
+	}
+
+	binWidth := float64(sampleRate) / spectrumSize
+	for k := range s.bins {
+		// DC takes the level of the first bin above it
+		f := math.Max(1, float64(k)) * binWidth
+		s.bins[k] = math.Sqrt(power(f))
+	}
+}
+
+// shapeFrame pushes half a frame of white noise through the shaper and
+// appends the finished half frame to ready.
+func (s *shaper) shapeFrame(rng *rand.Rand) {
+	const hop = spectrumSize / 2
+
+	copy(s.input, s.input[hop:])
+	for i := spectrumSize - hop; i < spectrumSize; i++ {
+		s.input[i] = rng.Float64()*2 - 1
+	}
+
+	for i, x := range s.input {
+		s.frame[i] = complex(x*s.window[i], 0)
+	}
+	fft.Forward(s.frame)
+	for k, a := range s.bins {
+		s.frame[k] *= complex(a, 0)
+		if k > 0 && k < spectrumSize/2 {
+			s.frame[spectrumSize-k] *= complex(a, 0)
+		}
+	}
+	fft.Inverse(s.frame)
+
+	// Sine windows on both ends overlap-add to unity at half-frame hops
+	for i := range hop {
+		s.ready = append(s.ready, s.overlap[i]+real(s.frame[i])*s.window[i])
+		s.overlap[i] = real(s.frame[hop+i]) * s.window[hop+i]
+	}
+}
+
+// generate returns shaped noise scaled by volume.
+func (s *shaper) generate(rng *rand.Rand, samples int, volume float64) []float64 {
+	for len(s.ready) < samples {
+		s.shapeFrame(rng)
+	}
+
+	result := make([]float64, samples)
+	for i := range result {
+		result[i] = s.ready[i] * volume
+	}
+	s.ready = append(s.ready[:0], s.ready[samples:]...)
+	return result
+}
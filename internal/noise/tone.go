@@ -0,0 +1,103 @@
+package noise
+
+import "math"
+
+// ToneMode selects how a Tone encodes its beat frequency.
+type ToneMode string
+
+const (
+	// ToneBinaural plays slightly different carriers in each ear; the brain
+	// hears their difference as a beat. Needs headphones.
+	ToneBinaural ToneMode = "binaural"
+	// ToneIsochronic pulses one carrier on and off at the beat frequency in
+	// both channels, so it also works on speakers.
+	ToneIsochronic ToneMode = "isochronic"
+)
+
+// ToneModes lists every selectable tone mode.
+var ToneModes = []ToneMode{ToneBinaural, ToneIsochronic}
+
+// ParseToneMode returns the ToneMode with the given name, defaulting to ToneBinaural.
+func ParseToneMode(name string) ToneMode {
+	for _, m := range ToneModes {
+		if string(m) == name {
+			return m
+		}
+	}
+	return ToneBinaural
+}
+
+// toneAmplitude is the tone's peak at volume 1, which puts it at about the
+// loudness of the calibrated noise.
+const toneAmplitude = 0.25
+
+// Tone synthesizes a stereo beat tone: binaural beats or isochronic pulses
+// at a carrier frequency.
+type Tone struct {
+	sampleRate int
+	mode       ToneMode
+	carrier    float64 // Hz
+	beat       float64 // Hz
+
+	phaseL, phaseR float64
+	pulse          float64 // isochronic pulse phase, 0-1
+}
+
+func NewTone(sampleRate int) *Tone {
+	return &Tone{
+		sampleRate: sampleRate,
+		mode:       ToneBinaural,
+		carrier:    200,
+		beat:       6,
+	}
+}
+
+func (t *Tone) SetMode(mode ToneMode) {
+	t.mode = mode
+}
+
+// SetCarrier sets the carrier frequency in Hz (50-1000).
+func (t *Tone) SetCarrier(hz float64) {
+	t.carrier = math.Max(50, math.Min(1000, hz))
+}
+
+// SetBeat sets the beat frequency in Hz (0.5-40).
+func (t *Tone) SetBeat(hz float64) {
+	t.beat = math.Max(0.5, math.Min(40, hz))
+}
+
+// Generate returns left and right channels. Binaural carriers are spread
+// half the beat either side of the carrier frequency.
+func (t *Tone) Generate(samples int, volume float64) ([]float64, []float64) {
+	left := make([]float64, samples)
+	right := make([]float64, samples)
+	dt := 1 / float64(t.sampleRate)
+	volume *= toneAmplitude
+
+	freqL, freqR := t.carrier, t.carrier
+	if t.mode == ToneBinaural {
+		freqL -= t.beat / 2
+		freqR += t.beat / 2
+	}
+
+	for i := range samples {
+		t.phaseL = math.Mod(t.phaseL+2*math.Pi*freqL*dt, 2*math.Pi)
+		t.phaseR = math.Mod(t.phaseR+2*math.Pi*freqR*dt, 2*math.Pi)
+
+		env := 1.0
+		if t.mode == ToneIsochronic {
+			// Raised-cosine pulse: on for half of each cycle with soft edges
+			// so the pulses don't click
+			t.pulse = math.Mod(t.pulse+t.beat*dt, 1)
+			if t.pulse < 0.5 {
+				env = (1 - math.Cos(4*math.Pi*t.pulse)) / 2
+			} else {
+				env = 0
+			}
+		}
+
+		left[i] = math.Sin(t.phaseL) * env * volume
+		right[i] = math.Sin(t.phaseR) * env * volume
+	}
+	return left, right
+}
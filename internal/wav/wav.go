@@ -0,0 +1,155 @@
+// Package wav decodes RIFF WAVE files.
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+const (
+	formatPCM        = 1
+	formatFloat      = 3
+	formatExtensible = 0xFFFE
+)
+
+// Limits on what the header may ask to be read into memory. The largest
+// standard fmt chunk, WAVE_FORMAT_EXTENSIBLE, is 40 bytes.
+const (
+	maxFormatSize = 256
+	maxChannels   = 8
+)
+
+// Audio is decoded audio with samples scaled to [-1, 1].
+type Audio struct {
+	SampleRate int
+	Channels   [][]float64
+}
+
+// Load decodes the WAV file at path; see Decode.
+func Load(path string, maxFrames int) (*Audio, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f, maxFrames)
+}
+
+// Decode reads a WAV stream of 8-, 16-, 24- or 32-bit integer PCM, or 32-
+// or 64-bit float samples, with up to 8 channels. Audio longer than
+// maxFrames frames is an error, so a bad header can't make it allocate
+// more.
+func Decode(r io.Reader, maxFrames int) (*Audio, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, errors.New("wav: not a RIFF WAVE file")
+	}
+
+	var format, channels, bits int
+	var sampleRate int
+	haveFormat := false
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, errors.New("wav: no data chunk")
+			}
+			return nil, err
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		// Chunks are padded to an even length
+		padded := size + size&1
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, errors.New("wav: fmt chunk too short")
+			}
+			if size > maxFormatSize {
+				return nil, errors.New("wav: fmt chunk too long")
+			}
+			data := make([]byte, padded)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, err
+			}
+			format = int(binary.LittleEndian.Uint16(data[0:2]))
+			channels = int(binary.LittleEndian.Uint16(data[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(data[4:8]))
+			bits = int(binary.LittleEndian.Uint16(data[14:16]))
+			if format == formatExtensible && size >= 26 {
+				// The sub-format GUID starts with the actual format tag
+				format = int(binary.LittleEndian.Uint16(data[24:26]))
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, errors.New("wav: data chunk before fmt chunk")
+			}
+			if channels > maxChannels {
+				return nil, fmt.Errorf("wav: too many channels (%d)", channels)
+			}
+			// decodeSamples rejects bad formats once read
+			frameSize := int64(max(1, channels*bits/8))
+			if size/frameSize > int64(maxFrames) {
+				return nil, fmt.Errorf("wav: longer than %d frames", maxFrames)
+			}
+			data := make([]byte, size)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, err
+			}
+			return decodeSamples(data, format, channels, sampleRate, bits)
+		default:
+			if _, err := io.CopyN(io.Discard, r, padded); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func decodeSamples(data []byte, format, channels, sampleRate, bits int) (*Audio, error) {
+	if channels < 1 {
+		return nil, errors.New("wav: no channels")
+	}
+	var sample func(b []byte) float64
+	switch {
+	case format == formatPCM && bits == 8:
+		sample = func(b []byte) float64 { return (float64(b[0]) - 128) / 128 }
+	case format == formatPCM && bits == 16:
+		sample = func(b []byte) float64 { return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15) }
+	case format == formatPCM && bits == 24:
+		sample = func(b []byte) float64 {
+			v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			return float64(v) / (1 << 23)
+		}
+	case format == formatPCM && bits == 32:
+		sample = func(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31) }
+	case format == formatFloat && bits == 32:
+		sample = func(b []byte) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) }
+	case format == formatFloat && bits == 64:
+		sample = func(b []byte) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b)) }
+	default:
+		return nil, fmt.Errorf("wav: unsupported format %d with %d bits", format, bits)
+	}
+
+	width := bits / 8
+	frames := len(data) / (width * channels)
+	audio := &Audio{SampleRate: sampleRate, Channels: make([][]float64, channels)}
+	for ch := range audio.Channels {
+		audio.Channels[ch] = make([]float64, frames)
+	}
+	for i := range frames {
+		for ch := range channels {
+			off := (i*channels + ch) * width
+			audio.Channels[ch][i] = sample(data[off : off+width])
+		}
+	}
+	return audio, nil
+}
@@ -0,0 +1,91 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// chunk returns a RIFF chunk with the given id and declared size holding
+// body.
+func chunk(id string, size uint32, body []byte) []byte {
+	b := append([]byte(id), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b[4:], size)
+	return append(b, body...)
+}
+
+// fmtBody returns a 16-byte fmt chunk body.
+func fmtBody(format, channels, sampleRate, bits int) []byte {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint16(b[0:], uint16(format))
+	binary.LittleEndian.PutUint16(b[2:], uint16(channels))
+	binary.LittleEndian.PutUint32(b[4:], uint32(sampleRate))
+	binary.LittleEndian.PutUint16(b[14:], uint16(bits))
+	return b
+}
+
+func riff(chunks ...[]byte) []byte {
+	b := []byte("RIFF\x00\x00\x00\x00WAVE")
+	for _, c := range chunks {
+		b = append(b, c...)
+	}
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	// Two stereo 16-bit frames: (0.5, -0.5), (-1, 0)
+	samples := []byte{0x00, 0x40, 0x00, 0xC0, 0x00, 0x80, 0x00, 0x00}
+	file := riff(
+		chunk("fmt ", 16, fmtBody(formatPCM, 2, 48000, 16)),
+		chunk("LIST", 3, []byte{1, 2, 3, 0}), // odd size, padded
+		chunk("data", 8, samples),
+	)
+	audio, err := Decode(bytes.NewReader(file), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audio.SampleRate != 48000 || len(audio.Channels) != 2 {
+		t.Fatalf("got %d Hz, %d channels", audio.SampleRate, len(audio.Channels))
+	}
+	want := [][]float64{{0.5, -1}, {-0.5, 0}}
+	for ch := range want {
+		for i := range want[ch] {
+			if audio.Channels[ch][i] != want[ch][i] {
+				t.Errorf("channel %d frame %d: got %g, want %g", ch, i, audio.Channels[ch][i], want[ch][i])
+			}
+		}
+	}
+}
+
+func TestDecodeRejectsBadHeaders(t *testing.T) {
+	format := chunk("fmt ", 16, fmtBody(formatPCM, 1, 44100, 16))
+	tests := []struct {
+		name string
+		file []byte
+		want string // substring of the error
+	}{
+		{"truncated RIFF header", []byte("RIFF\x00\x00"), "EOF"},
+		{"not WAVE", []byte("RIFF\x00\x00\x00\x00AVI "), "not a RIFF WAVE"},
+		{"no data chunk", riff(format), "no data chunk"},
+		{"truncated chunk header", riff(format, []byte("dat")), "no data chunk"},
+		{"data before fmt", riff(chunk("data", 2, []byte{0, 0})), "before fmt"},
+		{"fmt too short", riff(chunk("fmt ", 14, make([]byte, 14))), "too short"},
+		{"fmt too long", riff(chunk("fmt ", 0xFFFFFFF0, fmtBody(formatPCM, 1, 44100, 16))), "too long"},
+		{"truncated fmt", riff(chunk("fmt ", 40, fmtBody(formatPCM, 1, 44100, 16))), "EOF"},
+		{"too many channels", riff(chunk("fmt ", 16, fmtBody(formatPCM, 60000, 44100, 16)), chunk("data", 4, make([]byte, 4))), "too many channels"},
+		{"oversized data", riff(format, chunk("data", 0xFFFFFFF0, make([]byte, 64))), "longer than"},
+		{"oversized data without a sample size", riff(chunk("fmt ", 16, fmtBody(formatPCM, 1, 44100, 0)), chunk("data", 0xFFFFFFF0, make([]byte, 64))), "longer than"},
+		{"just over the limit", riff(format, chunk("data", 2*101, make([]byte, 2*101))), "longer than"},
+		{"truncated data", riff(format, chunk("data", 100, make([]byte, 10))), "EOF"},
+		{"unsupported format", riff(chunk("fmt ", 16, fmtBody(formatPCM, 1, 44100, 12)), chunk("data", 4, make([]byte, 4))), "unsupported format"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(tc.file), 100)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got error %v, want one containing %q", err, tc.want)
+			}
+		})
+	}
+}